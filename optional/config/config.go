@@ -0,0 +1,257 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/crypto"
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("invalid sessionx configuration")
+
+// File is the layout of a JSON or YAML configuration file. Unset fields
+// keep the session.DefaultConfig values.
+type File struct {
+	SecretKey        string     `json:"secret_key" yaml:"secret_key"`
+	OldSecretKeys    []string   `json:"old_secret_keys" yaml:"old_secret_keys"`
+	CookieName       string     `json:"cookie_name" yaml:"cookie_name"`
+	MaxAge           *Duration  `json:"max_age" yaml:"max_age"`
+	MaxSessionAge    *Duration  `json:"max_session_age" yaml:"max_session_age"`
+	Path             string     `json:"path" yaml:"path"`
+	Domain           string     `json:"domain" yaml:"domain"`
+	Secure           *bool      `json:"secure" yaml:"secure"`
+	HttpOnly         *bool      `json:"http_only" yaml:"http_only"`
+	SameSite         string     `json:"same_site" yaml:"same_site"`
+	Partitioned      *bool      `json:"partitioned" yaml:"partitioned"`
+	HostPrefix       bool       `json:"host_prefix" yaml:"host_prefix"`
+	RotationInterval *Duration  `json:"rotation_interval" yaml:"rotation_interval"`
+	CacheHeaders     *bool      `json:"cache_headers" yaml:"cache_headers"`
+	Store            *StoreSpec `json:"store" yaml:"store"`
+}
+
+type StoreSpec struct {
+	Type    string       `json:"type" yaml:"type"`
+	Options StoreOptions `json:"options" yaml:"options"`
+}
+
+// FromFile builds a Config from a .json, .yaml or .yml file. SESSIONX_*
+// environment variables override the file, so secrets can stay out of it.
+// opts are applied last.
+func FromFile(path string, opts ...session.ConfigOption) (session.Config, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return session.Config{}, err
+	}
+
+	env, err := fromEnv()
+	return build(opts, source{f, fileField(path), nil}, source{env, envField, err})
+}
+
+// FromEnv builds a Config from SESSIONX_* environment variables
+func FromEnv(opts ...session.ConfigOption) (session.Config, error) {
+	env, err := fromEnv()
+	return build(opts, source{env, envField, err})
+}
+
+type source struct {
+	file File
+	// field names a setting in error messages
+	field func(name string) string
+	// err holds the settings that could not be read, reported along with
+	// the others
+	err error
+}
+
+func build(opts []session.ConfigOption, sources ...source) (session.Config, error) {
+	cfg := session.DefaultConfig(nil)
+
+	var errs []error
+	hostPrefix, keyGiven := false, false
+	// Only the store of the last source selecting one is built, once the
+	// configuration is known to be valid, so none is left running
+	var storeSpec *StoreSpec
+	var storeSource source
+	for _, src := range sources {
+		if src.err != nil {
+			errs = append(errs, src.err)
+		}
+		errs = append(errs, apply(&cfg, src)...)
+		hostPrefix = hostPrefix || src.file.HostPrefix
+		keyGiven = keyGiven || src.file.SecretKey != ""
+		if src.file.Store != nil && src.file.Store.Type != "" {
+			storeSpec, storeSource = src.file.Store, src
+		}
+	}
+
+	// A key that failed to decode was already reported
+	if cfg.SecretKey == nil && !keyGiven {
+		errs = append(errs, fmt.Errorf("%w: %s is required", ErrInvalidConfig, sources[len(sources)-1].field("secret_key")))
+	}
+
+	if len(errs) > 0 {
+		return session.Config{}, errors.Join(errs...)
+	}
+
+	if hostPrefix {
+		session.WithHostPrefix()(&cfg)
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return session.Config{}, err
+	}
+
+	// A store passed in opts wins over the configured one
+	if storeSpec != nil && cfg.Store == nil {
+		store, err := newStore(storeSpec.Type, storeSpec.Options)
+		if err != nil {
+			return session.Config{}, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, storeSource.field("store"), err)
+		}
+		cfg.Store = store
+	}
+	return cfg, nil
+}
+
+func apply(cfg *session.Config, src source) []error {
+	var errs []error
+	f := src.file
+	invalid := func(name string, err error) {
+		errs = append(errs, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, src.field(name), err))
+	}
+
+	if f.SecretKey != "" {
+		key, err := crypto.DecodeKey(f.SecretKey)
+		if err != nil {
+			invalid("secret_key", err)
+		} else {
+			cfg.SecretKey = key
+		}
+	}
+
+	if len(f.OldSecretKeys) > 0 {
+		cfg.OldSecretKeys = nil
+		for i, encoded := range f.OldSecretKeys {
+			key, err := crypto.DecodeKey(encoded)
+			if err != nil {
+				invalid(fmt.Sprintf("old_secret_keys[%d]", i), err)
+				continue
+			}
+			cfg.OldSecretKeys = append(cfg.OldSecretKeys, key)
+		}
+	}
+
+	if f.CookieName != "" {
+		cfg.CookieName = f.CookieName
+	}
+	if f.MaxAge != nil {
+		cfg.MaxAge = time.Duration(*f.MaxAge)
+	}
+	if f.MaxSessionAge != nil {
+		cfg.MaxSessionAge = time.Duration(*f.MaxSessionAge)
+	}
+	if f.Path != "" {
+		cfg.Path = f.Path
+	}
+	if f.Domain != "" {
+		cfg.Domain = f.Domain
+	}
+	if f.Secure != nil {
+		cfg.Secure = *f.Secure
+	}
+	if f.HttpOnly != nil {
+		cfg.HttpOnly = *f.HttpOnly
+	}
+	if f.SameSite != "" {
+		cfg.SameSite = f.SameSite
+	}
+	if f.Partitioned != nil {
+		cfg.Partitioned = *f.Partitioned
+	}
+	if f.RotationInterval != nil {
+		cfg.RotationInterval = time.Duration(*f.RotationInterval)
+	}
+	if f.CacheHeaders != nil {
+		cfg.CacheHeaders = *f.CacheHeaders
+	}
+
+	return errs
+}
+
+func readFile(path string) (File, error) {
+	var f File
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	default:
+		return f, fmt.Errorf("%w: %s: unsupported file extension, use .json, .yaml or .yml", ErrInvalidConfig, path)
+	}
+
+	if err != nil {
+		return f, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+	return f, nil
+}
+
+func fileField(path string) func(string) string {
+	return func(name string) string {
+		return path + ": " + name
+	}
+}
+
+// Duration accepts Go duration strings ("2h", "15m") or a number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *Duration) set(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Second)))
+	case int:
+		*d = Duration(time.Duration(v) * time.Second)
+	default:
+		return fmt.Errorf("invalid duration %v", v)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey encodes differently in every base64 alphabet
+var testKey = bytes.Repeat([]byte{0xfb, 0xef, 0xbe}, 11)[:32]
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeyEncodings(t *testing.T) {
+	encodings := map[string]string{
+		"hex":            hex.EncodeToString(testKey),
+		"base64":         base64.StdEncoding.EncodeToString(testKey),
+		"raw base64":     base64.RawStdEncoding.EncodeToString(testKey),
+		"base64url":      base64.URLEncoding.EncodeToString(testKey),
+		"raw base64url":  base64.RawURLEncoding.EncodeToString(testKey),
+		"padded by tabs": "\t" + hex.EncodeToString(testKey) + "\n",
+	}
+
+	for name, encoded := range encodings {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SESSIONX_SECRET_KEY", encoded)
+			t.Setenv("SESSIONX_OLD_SECRET_KEYS", encoded+", "+hex.EncodeToString(testKey[:16]))
+
+			cfg, err := FromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(cfg.SecretKey, testKey) {
+				t.Fatalf("SecretKey = %x, want %x", cfg.SecretKey, testKey)
+			}
+			if len(cfg.OldSecretKeys) != 2 || !bytes.Equal(cfg.OldSecretKeys[1], testKey[:16]) {
+				t.Fatalf("OldSecretKeys = %x", cfg.OldSecretKeys)
+			}
+		})
+	}
+}
+
+func TestKeySizes(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		t.Setenv("SESSIONX_SECRET_KEY", hex.EncodeToString(testKey[:size]))
+		if _, err := FromEnv(); err != nil {
+			t.Errorf("%d-byte key: %v", size, err)
+		}
+	}
+
+	t.Setenv("SESSIONX_SECRET_KEY", hex.EncodeToString(testKey[:20]))
+	if _, err := FromEnv(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("20-byte key: error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestDurations(t *testing.T) {
+	key := hex.EncodeToString(testKey)
+
+	tests := []struct {
+		name, file, content string
+		want                time.Duration
+	}{
+		{"json string", "c.json", `{"secret_key": "` + key + `", "max_age": "2h"}`, 2 * time.Hour},
+		{"json seconds", "c.json", `{"secret_key": "` + key + `", "max_age": 90}`, 90 * time.Second},
+		{"json fraction", "c.json", `{"secret_key": "` + key + `", "max_age": 1.5}`, 1500 * time.Millisecond},
+		{"yaml string", "c.yaml", "secret_key: " + key + "\nmax_age: 15m\n", 15 * time.Minute},
+		{"yaml seconds", "c.yml", "secret_key: " + key + "\nmax_age: 60\n", time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := FromFile(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MaxAge != tt.want {
+				t.Fatalf("MaxAge = %v, want %v", cfg.MaxAge, tt.want)
+			}
+		})
+	}
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("SESSIONX_SECRET_KEY", key)
+		t.Setenv("SESSIONX_ROTATION_INTERVAL", "45m")
+
+		cfg, err := FromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.RotationInterval != 45*time.Minute {
+			t.Fatalf("RotationInterval = %v, want 45m", cfg.RotationInterval)
+		}
+	})
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "c.json", `{"secret_key": "`+hex.EncodeToString(testKey[:16])+`", "cookie_name": "file"}`)
+	t.Setenv("SESSIONX_SECRET_KEY", hex.EncodeToString(testKey))
+
+	cfg, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cfg.SecretKey, testKey) {
+		t.Fatal("SESSIONX_SECRET_KEY did not override the file")
+	}
+	if cfg.CookieName != "file" {
+		t.Fatalf("CookieName = %q, want the file value", cfg.CookieName)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	key := hex.EncodeToString(testKey)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			name: "missing key",
+			want: []string{"SESSIONX_SECRET_KEY is required"},
+		},
+		{
+			name: "every invalid field",
+			env: map[string]string{
+				"SESSIONX_SECRET_KEY": "not a key",
+				"SESSIONX_MAX_AGE":    "soon",
+				"SESSIONX_SECURE":     "maybe",
+			},
+			want: []string{"SESSIONX_SECRET_KEY: ", `SESSIONX_MAX_AGE: time: invalid duration "soon"`, "SESSIONX_SECURE: "},
+		},
+		{
+			name:    "invalid old key in file",
+			file:    "c.json",
+			content: `{"secret_key": "` + key + `", "old_secret_keys": ["` + key + `", "short"]}`,
+			want:    []string{"c.json: old_secret_keys[1]: "},
+		},
+		{
+			name:    "invalid duration in file",
+			file:    "c.yaml",
+			content: "secret_key: " + key + "\nmax_age: [1]\n",
+			want:    []string{"c.yaml: ", "invalid duration"},
+		},
+		{
+			name:    "unknown field",
+			file:    "c.json",
+			content: `{"secret_key": "` + key + `", "max_ages": "2h"}`,
+			want:    []string{"c.json: ", "max_ages"},
+		},
+		{
+			name:    "unsupported extension",
+			file:    "c.toml",
+			content: "secret_key = 'x'",
+			want:    []string{"c.toml: unsupported file extension"},
+		},
+		{
+			name:    "unknown store",
+			file:    "c.json",
+			content: `{"secret_key": "` + key + `", "store": {"type": "etcd"}}`,
+			want:    []string{"c.json: store: unknown store \"etcd\""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var err error
+			if tt.file != "" {
+				_, err = FromFile(writeFile(t, tt.file, tt.content))
+			} else {
+				_, err = FromEnv()
+			}
+
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("error = %v, want ErrInvalidConfig", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const envPrefix = "SESSIONX_"
+
+// envField maps a file field name to its environment variable
+func envField(name string) string {
+	return envPrefix + strings.ToUpper(name)
+}
+
+// fromEnv reads SESSIONX_* variables into a File. Store options are taken
+// from SESSIONX_STORE_<OPTION>, e.g. SESSIONX_STORE_ADDR for "addr".
+func fromEnv() (File, error) {
+	var f File
+	var errs []error
+
+	lookup := func(name string) (string, bool) {
+		v, ok := os.LookupEnv(envField(name))
+		return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+	}
+	invalid := func(name string, err error) {
+		errs = append(errs, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, envField(name), err))
+	}
+	boolean := func(name string) *bool {
+		v, ok := lookup(name)
+		if !ok {
+			return nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid(name, err)
+			return nil
+		}
+		return &b
+	}
+	duration := func(name string) *Duration {
+		v, ok := lookup(name)
+		if !ok {
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			invalid(name, err)
+			return nil
+		}
+		return (*Duration)(&d)
+	}
+
+	f.SecretKey, _ = lookup("secret_key")
+	if v, ok := lookup("old_secret_keys"); ok {
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				f.OldSecretKeys = append(f.OldSecretKeys, key)
+			}
+		}
+	}
+	f.CookieName, _ = lookup("cookie_name")
+	f.MaxAge = duration("max_age")
+	f.MaxSessionAge = duration("max_session_age")
+	f.Path, _ = lookup("path")
+	f.Domain, _ = lookup("domain")
+	f.Secure = boolean("secure")
+	f.HttpOnly = boolean("http_only")
+	f.SameSite, _ = lookup("same_site")
+	f.Partitioned = boolean("partitioned")
+	if hostPrefix := boolean("host_prefix"); hostPrefix != nil {
+		f.HostPrefix = *hostPrefix
+	}
+	f.RotationInterval = duration("rotation_interval")
+	f.CacheHeaders = boolean("cache_headers")
+
+	if storeType, ok := lookup("store"); ok {
+		f.Store = &StoreSpec{Type: storeType, Options: StoreOptions{}}
+		prefix := envField("store_")
+		for _, kv := range os.Environ() {
+			name, value, _ := strings.Cut(kv, "=")
+			if strings.HasPrefix(name, prefix) {
+				f.Store.Options[strings.ToLower(strings.TrimPrefix(name, prefix))] = value
+			}
+		}
+	}
+
+	return f, errors.Join(errs...)
+}
@@ -0,0 +1,12 @@
+module github.com/abmcmanu/sessionx/optional/config
+
+go 1.23.0
+
+toolchain go1.24.3
+
+require (
+	github.com/abmcmanu/sessionx v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/abmcmanu/sessionx => ../../
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/abmcmanu/sessionx/pkg/store/memory"
+)
+
+// StoreOptions are the free-form settings of a store section, e.g. "addr"
+type StoreOptions map[string]string
+
+// Duration parses a duration option, returning def when it is missing
+func (o StoreOptions) Duration(name string, def time.Duration) (time.Duration, error) {
+	v, ok := o[name]
+	if !ok || v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %v", name, err)
+	}
+	return d, nil
+}
+
+// Int parses an integer option, returning def when it is missing
+func (o StoreOptions) Int(name string, def int) (int, error) {
+	v, ok := o[name]
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %v", name, err)
+	}
+	return n, nil
+}
+
+type StoreFactory func(opts StoreOptions) (session.Store, error)
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]StoreFactory{
+		"memory": newMemoryStore,
+	}
+)
+
+// RegisterStore makes a store selectable by name. "memory" is built in;
+// the Redis store lives in its own module and is registered by the
+// application, see the package README.
+func RegisterStore(name string, factory StoreFactory) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	stores[name] = factory
+}
+
+func newStore(name string, opts StoreOptions) (session.Store, error) {
+	storesMu.RLock()
+	factory, ok := stores[name]
+	storesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown store %q, register it with config.RegisterStore", name)
+	}
+	return factory(opts)
+}
+
+func newMemoryStore(opts StoreOptions) (session.Store, error) {
+	ttl, err := opts.Duration("ttl", 0)
+	if err != nil {
+		return nil, err
+	}
+	cleanup, err := opts.Duration("cleanup_interval", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	return memory.NewMemoryStore(memory.Options{TTL: ttl, CleanupInterval: cleanup}), nil
+}
@@ -9,4 +9,9 @@ require (
 	github.com/redis/go-redis/v9 v9.5.5
 )
 
-replace github.com/abmcmanu/sessionx => ../../../
\ No newline at end of file
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+)
+
+replace github.com/abmcmanu/sessionx => ../../../
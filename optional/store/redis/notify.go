@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/redis/go-redis/v9"
+)
+
+// Notifier implements session.Notifier with Redis pub/sub so a logout on
+// one instance reaches the WebSockets held by every other. All instances
+// share a single channel; each one holds one subscription and fans the
+// changes out to its local watchers.
+type Notifier struct {
+	client  *redis.Client
+	channel string
+
+	mu          sync.Mutex
+	pubsub      *redis.PubSub
+	subscribers map[string]map[chan session.Change]struct{}
+}
+
+func NewNotifier(client *redis.Client, prefix string) *Notifier {
+	if prefix == "" {
+		prefix = "sessionx:"
+	}
+
+	return &Notifier{
+		client:      client,
+		channel:     prefix + "changes",
+		subscribers: make(map[string]map[chan session.Change]struct{}),
+	}
+}
+
+func (n *Notifier) Publish(ctx context.Context, c session.Change) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return n.client.Publish(ctx, n.channel, data).Err()
+}
+
+// Subscribe starts the shared subscription on first use
+func (n *Notifier) Subscribe(id string) (<-chan session.Change, func()) {
+	ch := make(chan session.Change, 1)
+
+	n.mu.Lock()
+	if n.pubsub == nil {
+		n.pubsub = n.client.Subscribe(context.Background(), n.channel)
+		// Wait for the confirmation so changes published right after
+		// Subscribe returns are not missed
+		_, _ = n.pubsub.Receive(context.Background())
+		go n.listen(n.pubsub)
+	}
+	if n.subscribers[id] == nil {
+		n.subscribers[id] = make(map[chan session.Change]struct{})
+	}
+	n.subscribers[id][ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			delete(n.subscribers[id], ch)
+			if len(n.subscribers[id]) == 0 {
+				delete(n.subscribers, id)
+			}
+		})
+	}
+	return ch, cancel
+}
+
+// listen delivers changes without blocking; a watcher that is not keeping
+// up misses one and relies on its next revalidation.
+func (n *Notifier) listen(pubsub *redis.PubSub) {
+	for msg := range pubsub.Channel() {
+		var c session.Change
+		if err := json.Unmarshal([]byte(msg.Payload), &c); err != nil {
+			continue
+		}
+
+		n.mu.Lock()
+		for ch := range n.subscribers[c.SessionID] {
+			select {
+			case ch <- c:
+			default:
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+// Close ends the shared subscription
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.pubsub == nil {
+		return nil
+	}
+	err := n.pubsub.Close()
+	n.pubsub = nil
+	return err
+}
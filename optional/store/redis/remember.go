@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/redis/go-redis/v9"
+)
+
+func (s *RedisStore) SaveRememberToken(t *session.RememberToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(t.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	userKey := s.rememberUserKey(t.UserID)
+
+	// The index must outlive the user's longest token; EXPIRE GT needs
+	// Redis 7, so compare by hand.
+	current, err := s.client.TTL(s.ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(s.ctx, s.rememberKey(t.Selector), data, ttl)
+		pipe.SAdd(s.ctx, userKey, t.Selector)
+		if current < ttl {
+			pipe.Expire(s.ctx, userKey, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) LoadRememberToken(selector string) (*session.RememberToken, error) {
+	data, err := s.client.Get(s.ctx, s.rememberKey(selector)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	var t session.RememberToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	return &t, nil
+}
+
+func (s *RedisStore) DeleteRememberToken(selector string) error {
+	t, err := s.LoadRememberToken(selector)
+	if err != nil {
+		return s.client.Del(s.ctx, s.rememberKey(selector)).Err()
+	}
+
+	_, err = s.client.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(s.ctx, s.rememberKey(selector))
+		pipe.SRem(s.ctx, s.rememberUserKey(t.UserID), selector)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) DeleteUserRememberTokens(userID string) error {
+	userKey := s.rememberUserKey(userID)
+
+	selectors, err := s.client.SMembers(s.ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(selectors)+1)
+	for _, selector := range selectors {
+		keys = append(keys, s.rememberKey(selector))
+	}
+	keys = append(keys, userKey)
+
+	return s.client.Del(s.ctx, keys...).Err()
+}
+
+func (s *RedisStore) rememberKey(selector string) string {
+	return s.prefix + "remember:" + selector
+}
+
+func (s *RedisStore) rememberUserKey(userID string) string {
+	return s.prefix + "remember:user:" + userID
+}
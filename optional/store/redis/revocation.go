@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationStore implements session.RevocationStore on Redis so
+// revocations reach every instance. Revoked IDs expire with the session
+// MaxAge; epochs never expire.
+type RevocationStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRevocationStore(client *redis.Client, prefix string) *RevocationStore {
+	if prefix == "" {
+		prefix = "sessionx:"
+	}
+
+	return &RevocationStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RevocationStore) RevokeID(ctx context.Context, id string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+"revoked:"+id, 1, ttl).Err()
+}
+
+func (s *RevocationStore) IsIDRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+"revoked:"+id).Result()
+	return n > 0, err
+}
+
+func (s *RevocationStore) Epoch(ctx context.Context, userID string) (uint64, error) {
+	epoch, err := s.client.Get(ctx, s.epochKey(userID)).Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+func (s *RevocationStore) IncrEpoch(ctx context.Context, userID string) error {
+	return s.client.Incr(ctx, s.epochKey(userID)).Err()
+}
+
+func (s *RevocationStore) epochKey(userID string) string {
+	if userID == "" {
+		return s.prefix + "epoch"
+	}
+	return s.prefix + "epoch:" + userID
+}
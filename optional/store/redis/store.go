@@ -11,7 +11,7 @@ import (
 )
 
 var (
-	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotFound = session.ErrSessionNotFound
 	ErrInvalidSession  = errors.New("invalid session data")
 )
 
@@ -60,7 +60,7 @@ func NewRedisStore(opts Options) (*RedisStore, error) {
 }
 
 func (s *RedisStore) Load(id string) (*session.Session, error) {
-	key := s.prefix + id
+	key := s.sessionKey(id)
 
 	data, err := s.client.Get(s.ctx, key).Bytes()
 	if err != nil {
@@ -79,19 +79,128 @@ func (s *RedisStore) Load(id string) (*session.Session, error) {
 }
 
 func (s *RedisStore) Save(sess *session.Session) error {
-	key := s.prefix + sess.ID
+	key := s.sessionKey(sess.ID)
 
 	data, err := json.Marshal(sess)
 	if err != nil {
 		return err
 	}
 
-	return s.client.Set(s.ctx, key, data, s.ttl).Err()
+	// A session given its own MaxAge keeps its record that long
+	ttl := s.ttl
+	if sess.MaxAge > 0 {
+		ttl = sess.MaxAge
+	}
+
+	if sess.UserID == "" {
+		return s.client.Set(s.ctx, key, data, ttl).Err()
+	}
+
+	userKey := s.userKey(sess.UserID)
+
+	// The index must outlive the user's longest session, see
+	// SaveRememberToken
+	current, err := s.client.TTL(s.ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(s.ctx, key, data, ttl)
+		pipe.SAdd(s.ctx, userKey, sess.ID)
+		if current < ttl {
+			pipe.Expire(s.ctx, userKey, ttl)
+		}
+		return nil
+	})
+	return err
 }
 
 func (s *RedisStore) Delete(id string) error {
-	key := s.prefix + id
-	return s.client.Del(s.ctx, key).Err()
+	key := s.sessionKey(id)
+
+	sess, err := s.Load(id)
+	if err != nil || sess.UserID == "" {
+		return s.client.Del(s.ctx, key).Err()
+	}
+
+	_, err = s.client.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(s.ctx, key)
+		pipe.SRem(s.ctx, s.userKey(sess.UserID), id)
+		return nil
+	})
+	return err
+}
+
+// ListByUser returns the sessions indexed under userID. Members whose
+// session has expired are removed from the index on the way.
+func (s *RedisStore) ListByUser(userID string) ([]session.SessionInfo, error) {
+	userKey := s.userKey(userID)
+
+	ids, err := s.client.SMembers(s.ctx, userKey).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+
+	values, err := s.client.MGet(s.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []session.SessionInfo
+	var stale []interface{}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+
+		var sess session.Session
+		if err := json.Unmarshal([]byte(data), &sess); err != nil || sess.UserID != userID {
+			stale = append(stale, ids[i])
+			continue
+		}
+		infos = append(infos, sess.Info())
+	}
+
+	if len(stale) > 0 {
+		_ = s.client.SRem(s.ctx, userKey, stale...).Err()
+	}
+
+	return infos, nil
+}
+
+func (s *RedisStore) DeleteByUser(userID string) error {
+	userKey := s.userKey(userID)
+
+	ids, err := s.client.SMembers(s.ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, s.sessionKey(id))
+	}
+	keys = append(keys, userKey)
+
+	return s.client.Del(s.ctx, keys...).Err()
+}
+
+// sessionKey keeps session records under their own namespace, apart from
+// the user index and the revocation and remember-me keys sharing the prefix
+func (s *RedisStore) sessionKey(id string) string {
+	return s.prefix + "sess:" + id
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + "user:" + userID
 }
 
 func (s *RedisStore) Close() error {
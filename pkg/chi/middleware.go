@@ -0,0 +1,121 @@
+package chi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+)
+
+type Option func(*options)
+
+type options struct {
+	readOnly       bool
+	skipSave       bool
+	maxAge         time.Duration
+	requireSession bool
+	unauthorized   http.Handler
+}
+
+// ReadOnly loads the session but refuses to save it, see Session.SetReadOnly
+func ReadOnly() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// SkipSave turns off the save at the end of the request; handlers can
+// still call Manager.Save
+func SkipSave() Option {
+	return func(o *options) {
+		o.skipSave = true
+	}
+}
+
+// WithMaxAge gives sessions saved by these routes their own lifetime, see
+// Session.SetMaxAge
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
+
+// RequireSession answers 401 when the client sent no valid session and
+// none was restored from a remember-me cookie. Requests skipped by
+// Config.Skipper carry no session and are answered 401 too.
+func RequireSession() Option {
+	return func(o *options) {
+		o.requireSession = true
+	}
+}
+
+// WithUnauthorizedHandler replaces the 401 response of RequireSession
+func WithUnauthorizedHandler(h http.Handler) Option {
+	return func(o *options) {
+		o.unauthorized = h
+	}
+}
+
+// Middleware applies opts to the routes it is mounted on:
+//
+//	r.Use(manager.Middleware)
+//	r.Group(func(r chi.Router) {
+//		r.Use(sessionchi.Middleware(manager, sessionchi.RequireSession()))
+//		r.Get("/account", account)
+//	})
+//
+// When the session was not loaded by an outer Manager.Middleware it is
+// loaded here, so the package also works as the only session middleware.
+func Middleware(manager *session.Manager, opts ...Option) func(http.Handler) http.Handler {
+	o := options{unauthorized: http.HandlerFunc(unauthorized)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		apply := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sess := session.Get(r)
+			if sess == nil {
+				// Skipped by Config.Skipper: there is no session to require
+				if o.requireSession {
+					o.unauthorized.ServeHTTP(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if o.requireSession && sess.IsNew() && !sess.Remembered {
+				// Don't hand out a cookie along with the 401
+				sess.SkipSave()
+				o.unauthorized.ServeHTTP(w, r)
+				return
+			}
+
+			if o.readOnly {
+				sess.SetReadOnly()
+			}
+			if o.skipSave {
+				sess.SkipSave()
+			}
+			if o.maxAge > 0 {
+				sess.SetMaxAge(o.maxAge)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+		loaded := manager.Middleware(apply)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if session.Get(r) == nil {
+				loaded.ServeHTTP(w, r)
+				return
+			}
+			apply.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
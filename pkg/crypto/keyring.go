@@ -1 +1,47 @@
 package crypto
+
+import "errors"
+
+var (
+	ErrInvalidKeySize = errors.New("key must be 16, 24, or 32 bytes")
+	ErrNoMatchingKey  = errors.New("no key could decrypt the data")
+)
+
+// Keyring encrypts with its primary key and decrypts with any of its keys,
+// so secrets can be rotated without logging everybody out: add the new key
+// as primary, keep the old one until its sessions have expired.
+type Keyring struct {
+	keys [][]byte
+}
+
+func NewKeyring(primary []byte, old ...[]byte) (*Keyring, error) {
+	keys := append([][]byte{primary}, old...)
+	for _, k := range keys {
+		if !ValidKeySize(k) {
+			return nil, ErrInvalidKeySize
+		}
+	}
+
+	return &Keyring{keys: keys}, nil
+}
+
+func (k *Keyring) Primary() []byte {
+	return k.keys[0]
+}
+
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return Seal(k.keys[0], plaintext)
+}
+
+func (k *Keyring) Decrypt(sealed []byte) ([]byte, error) {
+	if len(sealed) == 0 {
+		return nil, ErrCiphertextTooShort
+	}
+
+	for _, key := range k.keys {
+		if plaintext, err := Open(key, sealed); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrNoMatchingKey
+}
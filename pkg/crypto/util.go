@@ -1 +1,41 @@
 package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidKeyEncoding = errors.New("key is neither hex nor base64 of 16, 24, or 32 bytes")
+
+func ValidKeySize(key []byte) bool {
+	switch len(key) {
+	case 16, 24, 32:
+		return true
+	}
+	return false
+}
+
+// DecodeKey parses a key given as hex or base64 (standard or URL alphabet,
+// padded or not), as found in environment variables and config files.
+func DecodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+
+	if key, err := hex.DecodeString(s); err == nil && ValidKeySize(key) {
+		return key, nil
+	}
+
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	} {
+		if key, err := enc.DecodeString(s); err == nil && ValidKeySize(key) {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidKeyEncoding
+}
@@ -0,0 +1,126 @@
+package echo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	SessionKey = "sessionx"
+	ManagerKey = "sessionx.manager"
+)
+
+var ErrNoSession = errors.New("sessionx: session middleware not installed")
+
+func SessionMiddleware(manager *session.Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if manager.Skip(c.Request()) {
+				return next(c)
+			}
+
+			sess, req, ok := manager.Begin(c.Response(), c.Request())
+			if !ok {
+				return nil
+			}
+
+			c.Set(SessionKey, sess)
+			c.Set(ManagerKey, manager)
+
+			// Manager.Destroy and Renew look the session up on the request
+			c.SetRequest(req)
+
+			w := c.Response().Writer
+			saver := manager.NewSaver(w, req, sess)
+
+			// Save the session right before echo commits the headers. If the
+			// error handler answered instead, the handler's response is dropped.
+			c.Response().Before(func() {
+				saver.Save()
+				if saver.Aborted() {
+					c.Response().Writer = discardWriter{w}
+				}
+			})
+
+			err := next(c)
+
+			// Ensure session is saved even if no response was written. A
+			// returned error is rendered by echo's HTTPErrorHandler after
+			// this point, which triggers the Before hook instead.
+			if err == nil && !c.Response().Committed {
+				saver.Save()
+			}
+			return err
+		}
+	}
+}
+
+// discardWriter drops the handler's response after the error handler has
+// already answered
+type discardWriter struct {
+	http.ResponseWriter
+}
+
+func (discardWriter) WriteHeader(int) {}
+
+func (discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Get retrieves the session from the Echo context
+func Get(c echo.Context) *session.Session {
+	if sess, ok := c.Get(SessionKey).(*session.Session); ok {
+		return sess
+	}
+	return nil
+}
+
+func getManager(c echo.Context) *session.Manager {
+	manager, _ := c.Get(ManagerKey).(*session.Manager)
+	return manager
+}
+
+// Destroy expires the session cookie and stops the middleware from saving it
+func Destroy(c echo.Context) error {
+	manager := getManager(c)
+	if manager == nil {
+		return ErrNoSession
+	}
+	return manager.Destroy(c.Response(), c.Request())
+}
+
+// Renew regenerates the session ID and reissues the cookie, see session.Manager.Renew
+func Renew(c echo.Context, opts session.RenewOptions) error {
+	sess := Get(c)
+	manager := getManager(c)
+	if sess == nil || manager == nil {
+		return ErrNoSession
+	}
+	return manager.Renew(c.Response(), c.Request(), sess, opts)
+}
+
+// CSRF rejects unsafe requests without a valid token, see session.CSRF
+func CSRF(opts ...session.CSRFOption) echo.MiddlewareFunc {
+	cfg := session.DefaultCSRFConfig(opts...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := cfg.Verify(c.Request(), Get(c)); err != nil {
+				cfg.FailureHandler(c.Response(), c.Request(), err)
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
+// CSRFToken returns a masked CSRF token for the current session
+func CSRFToken(c echo.Context) string {
+	if sess := Get(c); sess != nil {
+		return sess.CSRFToken()
+	}
+	return ""
+}
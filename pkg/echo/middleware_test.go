@@ -0,0 +1,199 @@
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/labstack/echo/v4"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newManager(t *testing.T, opts ...session.ConfigOption) *session.Manager {
+	t.Helper()
+
+	manager, err := session.NewManager(session.DevConfig(testKey, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func newApp(manager *session.Manager) *echo.Echo {
+	e := echo.New()
+	e.Use(SessionMiddleware(manager))
+	return e
+}
+
+func serve(e *echo.Echo, method, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func sessionCookie(t *testing.T, manager *session.Manager, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == manager.CookieName() {
+			return c
+		}
+	}
+	t.Fatalf("no %s cookie in response", manager.CookieName())
+	return nil
+}
+
+// failingStore refuses every save
+type failingStore struct{}
+
+var errStoreDown = errors.New("store down")
+
+func (failingStore) Load(id string) (*session.Session, error) { return nil, session.ErrSessionNotFound }
+func (failingStore) Save(sess *session.Session) error         { return errStoreDown }
+func (failingStore) Delete(id string) error                   { return nil }
+
+func TestSaveBeforeResponse(t *testing.T) {
+	manager := newManager(t)
+	e := newApp(manager)
+	e.GET("/set", func(c echo.Context) error {
+		Get(c).Data["user"] = "alice"
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/get", func(c echo.Context) error {
+		user, _ := Get(c).Data["user"].(string)
+		return c.String(http.StatusOK, user)
+	})
+
+	rec := serve(e, http.MethodGet, "/set")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	cookie := sessionCookie(t, manager, rec)
+
+	rec = serve(e, http.MethodGet, "/get", cookie)
+	if got := rec.Body.String(); got != "alice" {
+		t.Fatalf("body = %q, want alice", got)
+	}
+}
+
+func TestSaveWithoutResponse(t *testing.T) {
+	manager := newManager(t)
+	e := newApp(manager)
+	e.GET("/", func(c echo.Context) error {
+		Get(c).Data["n"] = 1
+		return nil
+	})
+
+	sessionCookie(t, manager, serve(e, http.MethodGet, "/"))
+}
+
+func TestReturnedError(t *testing.T) {
+	manager := newManager(t)
+	e := newApp(manager)
+	e.GET("/", func(c echo.Context) error {
+		Get(c).Data["n"] = 1
+		return echo.NewHTTPError(http.StatusTeapot, "nope")
+	})
+
+	rec := serve(e, http.MethodGet, "/")
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want 418", rec.Code)
+	}
+	// Saved when the error handler commits the response
+	sessionCookie(t, manager, rec)
+}
+
+func TestSaveFailureAborts(t *testing.T) {
+	manager := newManager(t,
+		session.WithStore(failingStore{}),
+		session.WithErrorHandler(session.FailClosed(http.StatusServiceUnavailable)),
+	)
+	e := newApp(manager)
+	e.GET("/", func(c echo.Context) error {
+		Get(c).Data["n"] = 1
+		return c.String(http.StatusOK, "handler response")
+	})
+
+	rec := serve(e, http.MethodGet, "/")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	if body := rec.Body.String(); body == "handler response" {
+		t.Fatal("handler response was not dropped")
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	manager := newManager(t)
+	e := newApp(manager)
+	e.GET("/login", func(c echo.Context) error {
+		Get(c).Data["user"] = "alice"
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/logout", func(c echo.Context) error {
+		if err := Destroy(c); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	cookie := sessionCookie(t, manager, serve(e, http.MethodGet, "/login"))
+
+	expired := sessionCookie(t, manager, serve(e, http.MethodGet, "/logout", cookie))
+	if expired.MaxAge >= 0 {
+		t.Fatalf("cookie MaxAge = %d, want expired", expired.MaxAge)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	manager := newManager(t)
+	e := newApp(manager)
+	var ids []string
+	e.GET("/login", func(c echo.Context) error {
+		Get(c).Data["user"] = "alice"
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/renew", func(c echo.Context) error {
+		ids = append(ids, Get(c).ID)
+		if err := Renew(c, session.RenewOptions{}); err != nil {
+			return err
+		}
+		ids = append(ids, Get(c).ID)
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/get", func(c echo.Context) error {
+		user, _ := Get(c).Data["user"].(string)
+		return c.String(http.StatusOK, user)
+	})
+
+	cookie := sessionCookie(t, manager, serve(e, http.MethodGet, "/login"))
+
+	renewed := sessionCookie(t, manager, serve(e, http.MethodGet, "/renew", cookie))
+	if ids[0] == ids[1] {
+		t.Fatal("session ID was not regenerated")
+	}
+
+	rec := serve(e, http.MethodGet, "/get", renewed)
+	if got := rec.Body.String(); got != "alice" {
+		t.Fatalf("body = %q, want data kept across Renew", got)
+	}
+}
+
+func TestMissingMiddleware(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	if err := Destroy(c); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("Destroy error = %v, want ErrNoSession", err)
+	}
+	if err := Renew(c, session.RenewOptions{}); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("Renew error = %v, want ErrNoSession", err)
+	}
+}
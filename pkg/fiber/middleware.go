@@ -0,0 +1,178 @@
+package fiber
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	SessionKey = "sessionx"
+	ManagerKey = "sessionx.manager"
+)
+
+var ErrNoSession = errors.New("sessionx: session middleware not installed")
+
+// ErrorHandler handles load and save failures. Returning nil lets the
+// request continue; a non-nil error is passed on to fiber's ErrorHandler.
+type ErrorHandler func(c *fiber.Ctx, err error) error
+
+// DefaultErrorHandler logs err and lets the request continue, like
+// session.DefaultErrorHandler
+func DefaultErrorHandler(c *fiber.Ctx, err error) error {
+	log.Printf("sessionx: %s %s: %v", c.Method(), c.Path(), err)
+	return nil
+}
+
+type Option func(*options)
+
+type options struct {
+	errorHandler ErrorHandler
+}
+
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) {
+		o.errorHandler = h
+	}
+}
+
+// SessionMiddleware loads the session from the session cookie and saves it
+// once the handler chain returns. fasthttp buffers the response, so the
+// cookie can still be set at that point. Config.Transport, Binding and
+// RememberMe work on *http.Request and are not applied here.
+func SessionMiddleware(manager *session.Manager, opts ...Option) fiber.Handler {
+	o := options{errorHandler: DefaultErrorHandler}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		sess, err := manager.LoadValue(ctx, c.Cookies(manager.CookieName()))
+		if err != nil {
+			if err := o.errorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		c.Locals(SessionKey, sess)
+		c.Locals(ManagerKey, manager)
+		c.SetUserContext(session.NewContext(ctx, sess))
+
+		handlerErr := c.Next()
+
+		if sess.AutoSave() {
+			value, err := manager.SaveValue(c.UserContext(), sess)
+			if err != nil {
+				if err := o.errorHandler(c, err); err != nil {
+					return err
+				}
+			} else {
+				setCookie(c, manager.CookieFor(sess, value))
+				setCacheHeaders(c, manager)
+			}
+		}
+
+		return handlerErr
+	}
+}
+
+// setCookie writes cookie, replacing one already set under the same name.
+// The header is built by net/http so every attribute, Partitioned
+// included, survives the trip through fasthttp.
+func setCookie(c *fiber.Ctx, cookie *http.Cookie) {
+	c.Response().Header.DelCookie(cookie.Name)
+	c.Response().Header.Add(fiber.HeaderSetCookie, cookie.String())
+}
+
+// setCacheHeaders applies session.Manager.WriteCacheHeaders to the fasthttp
+// response
+func setCacheHeaders(c *fiber.Ctx, manager *session.Manager) {
+	h := http.Header{}
+	if v := c.GetRespHeader(fiber.HeaderCacheControl); v != "" {
+		h.Set(fiber.HeaderCacheControl, v)
+	}
+	if v := c.GetRespHeader(fiber.HeaderVary); v != "" {
+		h.Set(fiber.HeaderVary, v)
+	}
+
+	manager.WriteCacheHeaders(h)
+
+	if v := h.Get(fiber.HeaderCacheControl); v != "" {
+		c.Set(fiber.HeaderCacheControl, v)
+	}
+	if vary := h.Values(fiber.HeaderVary); len(vary) > 0 {
+		c.Set(fiber.HeaderVary, strings.Join(vary, ", "))
+	}
+}
+
+// Get retrieves the session from the Fiber context
+func Get(c *fiber.Ctx) *session.Session {
+	if sess, ok := c.Locals(SessionKey).(*session.Session); ok {
+		return sess
+	}
+	return nil
+}
+
+func getManager(c *fiber.Ctx) *session.Manager {
+	manager, _ := c.Locals(ManagerKey).(*session.Manager)
+	return manager
+}
+
+// Destroy expires the session cookie and stops the middleware from saving it
+func Destroy(c *fiber.Ctx) error {
+	manager := getManager(c)
+	if manager == nil {
+		return ErrNoSession
+	}
+
+	setCookie(c, manager.ExpiredCookie())
+	setCacheHeaders(c, manager)
+	return manager.DestroyValue(c.UserContext(), c.Cookies(manager.CookieName()), Get(c))
+}
+
+// Renew regenerates the session ID, see session.Manager.Renew. The new
+// cookie is written when the middleware saves the session.
+func Renew(c *fiber.Ctx, opts session.RenewOptions) error {
+	sess := Get(c)
+	manager := getManager(c)
+	if sess == nil || manager == nil {
+		return ErrNoSession
+	}
+	return manager.RenewValue(c.UserContext(), c.Cookies(manager.CookieName()), sess, opts)
+}
+
+// CSRF rejects unsafe requests without a valid token with 403, see
+// session.CSRF. The failure handler of the options is not used; the error
+// goes to fiber's ErrorHandler instead.
+func CSRF(opts ...session.CSRFOption) fiber.Handler {
+	cfg := session.DefaultCSRFConfig(opts...)
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Exempt(c.Method(), c.Path()) {
+			return c.Next()
+		}
+
+		token := c.Get(cfg.HeaderName)
+		if token == "" {
+			token = c.FormValue(cfg.FieldName)
+		}
+		if err := cfg.VerifyToken(Get(c), token); err != nil {
+			return fiber.NewError(fiber.StatusForbidden, http.StatusText(http.StatusForbidden))
+		}
+		return c.Next()
+	}
+}
+
+// CSRFToken returns a masked CSRF token for the current session
+func CSRFToken(c *fiber.Ctx) string {
+	if sess := Get(c); sess != nil {
+		return sess.CSRFToken()
+	}
+	return ""
+}
@@ -1,82 +1,110 @@
 package gin
 
 import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+
 	"github.com/abmcmanu/sessionx/pkg/session"
 	"github.com/gin-gonic/gin"
 )
 
-const SessionKey = "sessionx"
+const (
+	SessionKey = "sessionx"
+	ManagerKey = "sessionx.manager"
+)
+
+var ErrNoSession = errors.New("sessionx: session middleware not installed")
 
 func SessionMiddleware(manager *session.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sess, _ := manager.Load(c.Request)
+		if manager.Skip(c.Request) {
+			c.Next()
+			return
+		}
+
+		sess, req, ok := manager.Begin(c.Writer, c.Request)
+		if !ok {
+			c.Abort()
+			return
+		}
 
 		c.Set(SessionKey, sess)
+		c.Set(ManagerKey, manager)
+
+		// Manager.Destroy and Renew look the session up on the request
+		c.Request = req
 
 		// Wrap the response writer to intercept Write/WriteHeader calls
 		wrapped := &responseWriterWrapper{
 			ResponseWriter: c.Writer,
-			session:        sess,
-			manager:        manager,
+			saver:          manager.NewSaver(c.Writer, req, sess),
 		}
 		c.Writer = wrapped
 
 		c.Next()
 
 		// Ensure session is saved even if no response was written
-		wrapped.ensureSaved()
+		wrapped.saver.Save()
 	}
 }
 
-// responseWriterWrapper wraps gin.ResponseWriter to save session before writing response
+// responseWriterWrapper saves the session before gin commits the headers.
+// The saving itself is session.Saver, shared with Manager.Middleware.
 type responseWriterWrapper struct {
 	gin.ResponseWriter
-	session *session.Session
-	manager *session.Manager
-	saved   bool
+	saver *session.Saver
 }
 
 // WriteHeader saves the session before writing the status code
 func (rw *responseWriterWrapper) WriteHeader(status int) {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return
 	}
 	rw.ResponseWriter.WriteHeader(status)
 }
 
 // Write saves the session before writing the response body
 func (rw *responseWriterWrapper) Write(b []byte) (int, error) {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return len(b), nil
 	}
 	return rw.ResponseWriter.Write(b)
 }
 
 // WriteString saves the session before writing a string
 func (rw *responseWriterWrapper) WriteString(s string) (int, error) {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return len(s), nil
 	}
 	return rw.ResponseWriter.WriteString(s)
 }
 
 func (rw *responseWriterWrapper) WriteHeaderNow() {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return
 	}
 	rw.ResponseWriter.WriteHeaderNow()
 }
 
-// ensureSaved guarantees the session is saved even if no write occurred
-func (rw *responseWriterWrapper) ensureSaved() {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+// Flush saves the session before committing the headers
+func (rw *responseWriterWrapper) Flush() {
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return
 	}
+	rw.ResponseWriter.Flush()
+}
+
+// Hijack saves the session before handing over the connection
+func (rw *responseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.saver.Save()
+	return rw.ResponseWriter.Hijack()
 }
 
 // Get retrieves the session from the Gin context
@@ -86,3 +114,116 @@ func Get(c *gin.Context) *session.Session {
 	}
 	return nil
 }
+
+// Destroy expires the session cookie and stops the middleware from saving it
+func Destroy(c *gin.Context) error {
+	manager := getManager(c)
+	if manager == nil {
+		return ErrNoSession
+	}
+	return manager.Destroy(c.Writer, c.Request)
+}
+
+// Renew regenerates the session ID and reissues the cookie, see session.Manager.Renew
+func Renew(c *gin.Context, opts session.RenewOptions) error {
+	sess := Get(c)
+	manager := getManager(c)
+	if sess == nil || manager == nil {
+		return ErrNoSession
+	}
+	return manager.Renew(c.Writer, c.Request, sess, opts)
+}
+
+// CSRF aborts unsafe requests without a valid token, see session.CSRF
+func CSRF(opts ...session.CSRFOption) gin.HandlerFunc {
+	cfg := session.DefaultCSRFConfig(opts...)
+
+	return func(c *gin.Context) {
+		if err := cfg.Verify(c.Request, Get(c)); err != nil {
+			cfg.FailureHandler(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CSRFToken returns a masked CSRF token for the current session
+func CSRFToken(c *gin.Context) string {
+	if sess := Get(c); sess != nil {
+		return sess.CSRFToken()
+	}
+	return ""
+}
+
+func getManager(c *gin.Context) *session.Manager {
+	if v, exists := c.Get(ManagerKey); exists {
+		return v.(*session.Manager)
+	}
+	return nil
+}
+
+// HandleError passes err to the manager's ErrorHandler and aborts the
+// chain if the handler wrote a response
+func HandleError(c *gin.Context, err error) {
+	manager := getManager(c)
+	if manager == nil {
+		session.DefaultErrorHandler(c.Writer, c.Request, err)
+	} else {
+		manager.HandleError(c.Writer, c.Request, err)
+	}
+	if c.Writer.Written() {
+		c.Abort()
+	}
+}
+
+// RequireAuth aborts with 401 unless the session holds a value under key,
+// e.g. "user_id"
+func RequireAuth(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess := Get(c)
+		if sess == nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if _, ok := sess.Data[key]; !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ReadOnly marks the session of the route read-only, see
+// session.Session.SetReadOnly
+func ReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sess := Get(c); sess != nil {
+			sess.SetReadOnly()
+		}
+		c.Next()
+	}
+}
+
+// AddFlash queues a flash message, see session.Session.AddFlash
+func AddFlash(c *gin.Context, key string, value interface{}) {
+	if sess := Get(c); sess != nil {
+		sess.AddFlash(key, value)
+	}
+}
+
+// GetFlash returns and removes a flash message
+func GetFlash(c *gin.Context, key string) (interface{}, bool) {
+	if sess := Get(c); sess != nil {
+		return sess.GetFlash(key)
+	}
+	return nil, false
+}
+
+// Flashes returns and removes all flash messages
+func Flashes(c *gin.Context) map[string]interface{} {
+	if sess := Get(c); sess != nil {
+		return sess.GetFlashes()
+	}
+	return map[string]interface{}{}
+}
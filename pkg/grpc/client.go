@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Jar holds the session token of a client between calls
+type Jar struct {
+	mu    sync.Mutex
+	token string
+}
+
+func NewJar(token string) *Jar {
+	return &Jar{token: token}
+}
+
+func (j *Jar) Token() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.token
+}
+
+func (j *Jar) SetToken(token string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.token = token
+}
+
+// update takes the token from the response header or trailer. The server
+// sends an empty value when the session was destroyed.
+func (j *Jar) update(key string, mds ...metadata.MD) {
+	for _, md := range mds {
+		if v := md.Get(key); len(v) > 0 {
+			j.SetToken(v[len(v)-1])
+		}
+	}
+}
+
+// WithToken forwards token under MetadataKey on the outgoing calls made
+// with ctx, e.g. the cookie value a web frontend received from the
+// browser. It takes precedence over the Jar of a client interceptor.
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, token)
+}
+
+// UnaryClientInterceptor sends the token of jar with every call and stores
+// the token the server returns. Only WithMetadataKey applies to clients.
+func UnaryClientInterceptor(jar *Jar, opts ...Option) grpc.UnaryClientInterceptor {
+	o := defaultOptions(opts)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		var header, trailer metadata.MD
+		callOpts = append(callOpts, grpc.Header(&header), grpc.Trailer(&trailer))
+
+		err := invoker(o.outgoing(ctx, jar), method, req, reply, cc, callOpts...)
+		jar.update(o.key, header, trailer)
+		return err
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streams. The token
+// is stored once the server closes the stream.
+func StreamClientInterceptor(jar *Jar, opts ...Option) grpc.StreamClientInterceptor {
+	o := defaultOptions(opts)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(o.outgoing(ctx, jar), desc, cc, method, callOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, jar: jar, key: o.key, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// outgoing adds the jar token unless the caller set one with WithToken
+func (o options) outgoing(ctx context.Context, jar *Jar) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(o.key)) > 0 {
+		return ctx
+	}
+	if token := jar.Token(); token != "" {
+		return metadata.AppendToOutgoingContext(ctx, o.key, token)
+	}
+	return ctx
+}
+
+// clientStream picks up the token from the trailer when the stream ends:
+// on the final error of a server stream, or on the single response of a
+// client-streaming call (CloseAndRecv)
+type clientStream struct {
+	grpc.ClientStream
+	jar           *Jar
+	key           string
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() {
+			if header, herr := s.ClientStream.Header(); herr == nil {
+				s.jar.update(s.key, header)
+			}
+			s.jar.update(s.key, s.ClientStream.Trailer())
+		})
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKey carries the session token in both directions by default
+const MetadataKey = "x-session-token"
+
+var ErrNoSession = errors.New("sessionx: session interceptor not installed")
+
+// ErrorHandler handles load and save failures. Returning nil lets the call
+// continue; a non-nil error fails the call with it.
+type ErrorHandler func(ctx context.Context, method string, err error) error
+
+// DefaultErrorHandler logs err and lets the call continue, like
+// session.DefaultErrorHandler
+func DefaultErrorHandler(ctx context.Context, method string, err error) error {
+	log.Printf("sessionx: %s: %v", method, err)
+	return nil
+}
+
+type Option func(*options)
+
+type options struct {
+	key          string
+	cookies      bool
+	errorHandler ErrorHandler
+}
+
+func defaultOptions(opts []Option) options {
+	o := options{key: MetadataKey, cookies: true, errorHandler: DefaultErrorHandler}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMetadataKey changes the metadata key of the session token. gRPC
+// requires lower-case keys.
+func WithMetadataKey(key string) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+// WithCookies controls whether the server falls back to the session cookie
+// in a forwarded "cookie" metadata entry. On by default.
+func WithCookies(enabled bool) Option {
+	return func(o *options) {
+		o.cookies = enabled
+	}
+}
+
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) {
+		o.errorHandler = h
+	}
+}
+
+// call is what the interceptors keep in the context of a call
+type call struct {
+	manager *session.Manager
+	session *session.Session
+	token   string
+}
+
+type callKey struct{}
+
+// UnaryServerInterceptor loads the session before the handler and returns
+// the updated token in the response header, or the trailer when the
+// handler already sent the header.
+func UnaryServerInterceptor(manager *session.Manager, opts ...Option) grpc.UnaryServerInterceptor {
+	o := defaultOptions(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, c, err := o.load(ctx, manager, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, handlerErr := handler(ctx, req)
+
+		md, err := o.save(ctx, c, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if md != nil && grpc.SetHeader(ctx, md) != nil {
+			_ = grpc.SetTrailer(ctx, md)
+		}
+
+		return resp, handlerErr
+	}
+}
+
+// StreamServerInterceptor loads the session when the stream opens and
+// returns the updated token in the trailer once the handler is done.
+func StreamServerInterceptor(manager *session.Manager, opts ...Option) grpc.StreamServerInterceptor {
+	o := defaultOptions(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, c, err := o.load(ss.Context(), manager, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		handlerErr := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+
+		md, err := o.save(ctx, c, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if md != nil {
+			ss.SetTrailer(md)
+		}
+
+		return handlerErr
+	}
+}
+
+// serverStream hands the session context to the stream handler
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (o options) load(ctx context.Context, manager *session.Manager, method string) (context.Context, *call, error) {
+	token := o.token(ctx, manager.CookieName())
+
+	sess, err := manager.LoadValue(ctx, token)
+	if err != nil {
+		if err := o.errorHandler(ctx, method, err); err != nil {
+			return ctx, nil, err
+		}
+	}
+
+	c := &call{manager: manager, session: sess, token: token}
+	ctx = context.WithValue(ctx, callKey{}, c)
+	ctx = session.NewContext(ctx, sess)
+	return ctx, c, nil
+}
+
+// save returns the metadata to send back, or nil when there is nothing to
+// send. A destroyed session sends an empty token.
+func (o options) save(ctx context.Context, c *call, method string) (metadata.MD, error) {
+	if c.session.IsDestroyed() {
+		return metadata.Pairs(o.key, ""), nil
+	}
+	if !c.session.AutoSave() {
+		return nil, nil
+	}
+
+	value, err := c.manager.SaveValue(ctx, c.session)
+	if err != nil {
+		return nil, o.errorHandler(ctx, method, err)
+	}
+	return metadata.Pairs(o.key, value), nil
+}
+
+// token reads the session token from the incoming metadata, falling back
+// to the session cookie of a forwarded cookie header
+func (o options) token(ctx context.Context, cookieName string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if v := md.Get(o.key); len(v) > 0 && v[0] != "" {
+		return v[0]
+	}
+
+	if o.cookies {
+		for _, line := range md.Get("cookie") {
+			cookies, err := http.ParseCookie(line)
+			if err != nil {
+				continue
+			}
+			for _, c := range cookies {
+				if c.Name == cookieName && c.Value != "" {
+					return c.Value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Get retrieves the session of the call
+func Get(ctx context.Context) *session.Session {
+	if c, ok := ctx.Value(callKey{}).(*call); ok {
+		return c.session
+	}
+	return nil
+}
+
+// Destroy deletes the session; the interceptor sends an empty token back
+func Destroy(ctx context.Context) error {
+	c, ok := ctx.Value(callKey{}).(*call)
+	if !ok {
+		return ErrNoSession
+	}
+	return c.manager.DestroyValue(ctx, c.token, c.session)
+}
+
+// Renew regenerates the session ID, see session.Manager.Renew. The new
+// token is sent when the interceptor saves the session.
+func Renew(ctx context.Context, opts session.RenewOptions) error {
+	c, ok := ctx.Value(callKey{}).(*call)
+	if !ok {
+		return ErrNoSession
+	}
+	return c.manager.RenewValue(ctx, c.token, c.session, opts)
+}
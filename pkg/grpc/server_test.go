@@ -0,0 +1,267 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// counterServer keeps a counter in the session. The service is described
+// by hand so the tests need no generated code.
+type counterServer struct{}
+
+func count(sess *session.Session) int64 {
+	n, _ := sess.Data["n"].(float64)
+	return int64(n)
+}
+
+func add(sess *session.Session, delta int64) int64 {
+	n := count(sess) + delta
+	sess.Data["n"] = float64(n)
+	return n
+}
+
+var counterDesc = grpc.ServiceDesc{
+	ServiceName: "sessionx.test.Counter",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Incr", Handler: unaryHandler("/sessionx.test.Counter/Incr", func(ctx context.Context) (interface{}, error) {
+			return wrapperspb.Int64(add(Get(ctx), 1)), nil
+		})},
+		{MethodName: "Get", Handler: unaryHandler("/sessionx.test.Counter/Get", func(ctx context.Context) (interface{}, error) {
+			return wrapperspb.Int64(count(Get(ctx))), nil
+		})},
+		{MethodName: "Logout", Handler: unaryHandler("/sessionx.test.Counter/Logout", func(ctx context.Context) (interface{}, error) {
+			return &emptypb.Empty{}, Destroy(ctx)
+		})},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Sum", ClientStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			sess := Get(stream.Context())
+			for {
+				var v wrapperspb.Int64Value
+				err := stream.RecvMsg(&v)
+				if errors.Is(err, io.EOF) {
+					return stream.SendMsg(wrapperspb.Int64(count(sess)))
+				}
+				if err != nil {
+					return err
+				}
+				add(sess, v.Value)
+			}
+		}},
+		{StreamName: "Countdown", ServerStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			sess := Get(stream.Context())
+			for count(sess) > 0 {
+				if err := stream.SendMsg(wrapperspb.Int64(add(sess, -1))); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	},
+}
+
+func unaryHandler(method string, fn func(ctx context.Context) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		if err := dec(&emptypb.Empty{}); err != nil {
+			return nil, err
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: method}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(ctx)
+		}
+		if interceptor == nil {
+			return handler(ctx, &emptypb.Empty{})
+		}
+		return interceptor(ctx, &emptypb.Empty{}, info, handler)
+	}
+}
+
+// dial starts a server with the session interceptors over bufconn and
+// returns a client connection keeping its token in jar
+func dial(t *testing.T, jar *Jar) *grpc.ClientConn {
+	t.Helper()
+
+	manager, err := session.NewManager(session.DevConfig(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(manager)),
+		grpc.StreamInterceptor(StreamServerInterceptor(manager)),
+	)
+	srv.RegisterService(&counterDesc, counterServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(jar)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(jar)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func invoke(t *testing.T, conn *grpc.ClientConn, method string) int64 {
+	t.Helper()
+
+	var out wrapperspb.Int64Value
+	if err := conn.Invoke(context.Background(), "/sessionx.test.Counter/"+method, &emptypb.Empty{}, &out); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	return out.Value
+}
+
+func TestUnaryRoundTrip(t *testing.T) {
+	jar := NewJar("")
+	conn := dial(t, jar)
+
+	invoke(t, conn, "Incr")
+	if jar.Token() == "" {
+		t.Fatal("no token stored after the first call")
+	}
+	if n := invoke(t, conn, "Incr"); n != 2 {
+		t.Fatalf("counter = %d, want 2", n)
+	}
+}
+
+func TestUnaryDestroy(t *testing.T) {
+	jar := NewJar("")
+	conn := dial(t, jar)
+
+	invoke(t, conn, "Incr")
+
+	var out emptypb.Empty
+	if err := conn.Invoke(context.Background(), "/sessionx.test.Counter/Logout", &emptypb.Empty{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if token := jar.Token(); token != "" {
+		t.Fatalf("token = %q after Logout, want empty", token)
+	}
+	if n := invoke(t, conn, "Get"); n != 0 {
+		t.Fatalf("counter = %d after Logout, want 0", n)
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	jar := NewJar("")
+	conn := dial(t, jar)
+
+	invoke(t, conn, "Incr")
+	token := jar.Token()
+
+	// Another client forwarding the token sees the same session
+	var out wrapperspb.Int64Value
+	ctx := WithToken(context.Background(), token)
+	if err := conn.Invoke(ctx, "/sessionx.test.Counter/Get", &emptypb.Empty{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != 1 {
+		t.Fatalf("counter = %d, want 1", out.Value)
+	}
+}
+
+func TestClientStreamRoundTrip(t *testing.T) {
+	jar := NewJar("")
+	conn := dial(t, jar)
+
+	desc := &grpc.StreamDesc{StreamName: "Sum", ClientStreams: true}
+	stream, err := conn.NewStream(context.Background(), desc, "/sessionx.test.Counter/Sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []int64{2, 3} {
+		if err := stream.SendMsg(wrapperspb.Int64(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	var sum wrapperspb.Int64Value
+	if err := stream.RecvMsg(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Value != 5 {
+		t.Fatalf("sum = %d, want 5", sum.Value)
+	}
+
+	// The token came back in the trailer of CloseAndRecv
+	if jar.Token() == "" {
+		t.Fatal("no token stored after the client stream")
+	}
+	if n := invoke(t, conn, "Get"); n != 5 {
+		t.Fatalf("counter = %d, want 5", n)
+	}
+}
+
+func TestServerStreamRoundTrip(t *testing.T) {
+	jar := NewJar("")
+	conn := dial(t, jar)
+
+	invoke(t, conn, "Incr")
+	invoke(t, conn, "Incr")
+
+	desc := &grpc.StreamDesc{StreamName: "Countdown", ServerStreams: true}
+	stream, err := conn.NewStream(context.Background(), desc, "/sessionx.test.Counter/Countdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for {
+		var v wrapperspb.Int64Value
+		err := stream.RecvMsg(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v.Value)
+	}
+	if len(got) != 2 || got[1] != 0 {
+		t.Fatalf("countdown = %v, want [1 0]", got)
+	}
+
+	// The session saved at the end of the stream is the one used next
+	if n := invoke(t, conn, "Get"); n != 0 {
+		t.Fatalf("counter = %d after the stream, want 0", n)
+	}
+}
+
+func TestMissingInterceptor(t *testing.T) {
+	if Get(context.Background()) != nil {
+		t.Fatal("Get returned a session without the interceptor")
+	}
+	if err := Destroy(context.Background()); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("Destroy error = %v, want ErrNoSession", err)
+	}
+}
@@ -0,0 +1,201 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// BindingPolicy decides what Load does when a fingerprint component of the
+// request differs from the one recorded when the session was created.
+type BindingPolicy int
+
+const (
+	// BindingIgnore leaves the component out of the fingerprint
+	BindingIgnore BindingPolicy = iota
+	// BindingEvent only fires Hooks.OnBindingMismatch
+	BindingEvent
+	// BindingRotate issues a new session ID and keeps the data
+	BindingRotate
+	// BindingReject discards the session and starts a new one
+	BindingReject
+)
+
+// Binding ties a session to the client it was issued to. Components are
+// stored as keyed hashes, never in clear text.
+type Binding struct {
+	UserAgent      BindingPolicy
+	AcceptLanguage BindingPolicy
+	IP             BindingPolicy
+	// IPPrefix compares the network of the client IP, which tolerates
+	// mobile clients moving between addresses of the same carrier.
+	IPPrefix      BindingPolicy
+	TLSClientCert BindingPolicy
+
+	IPv4PrefixBits int
+	IPv6PrefixBits int
+
+	// ClientIP extracts the client address, defaulting to r.RemoteAddr.
+	// Set it when running behind a trusted proxy.
+	ClientIP func(r *http.Request) string
+}
+
+func WithBinding(b Binding) ConfigOption {
+	return func(c *Config) {
+		if b.IPv4PrefixBits == 0 {
+			b.IPv4PrefixBits = 24
+		}
+		if b.IPv6PrefixBits == 0 {
+			b.IPv6PrefixBits = 64
+		}
+		c.Binding = &b
+	}
+}
+
+type bindingComponent struct {
+	name   string
+	policy BindingPolicy
+	value  func(r *http.Request) string
+}
+
+func (b *Binding) components() []bindingComponent {
+	return []bindingComponent{
+		{"ua", b.UserAgent, func(r *http.Request) string { return r.UserAgent() }},
+		{"lang", b.AcceptLanguage, func(r *http.Request) string { return r.Header.Get("Accept-Language") }},
+		{"ip", b.IP, b.clientIP},
+		{"net", b.IPPrefix, b.clientNetwork},
+		{"cert", b.TLSClientCert, clientCert},
+	}
+}
+
+func (b *Binding) clientIP(r *http.Request) string {
+	if b.ClientIP != nil {
+		return b.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (b *Binding) clientNetwork(r *http.Request) string {
+	ip := net.ParseIP(b.clientIP(r))
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(b.IPv4PrefixBits, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(b.IPv6PrefixBits, 128)).String()
+}
+
+func clientCert(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+	return string(sum[:])
+}
+
+func (m *Manager) fingerprint(r *http.Request) map[string]string {
+	fp := map[string]string{}
+	for _, c := range m.cfg.Binding.components() {
+		if c.policy == BindingIgnore {
+			continue
+		}
+		fp[c.name] = fingerprintHash(m.fingerprintKeys[0], c.name, c.value(r))
+	}
+	return fp
+}
+
+// fingerprintKey derives the HMAC key of fingerprints from a secret key, so
+// the bytes used for encryption never key anything else
+func fingerprintKey(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("sessionx fingerprint"))
+	return mac.Sum(nil)
+}
+
+func fingerprintHash(key []byte, name, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// fingerprintMatches also accepts hashes made with OldSecretKeys so that
+// rotating the secret does not look like a different client. Hashes keyed
+// with a secret key itself, as recorded by earlier versions, are accepted
+// too; a match records the fingerprint again with the derived key.
+func (m *Manager) fingerprintMatches(recorded, current string, c bindingComponent, r *http.Request) bool {
+	if hmac.Equal([]byte(recorded), []byte(current)) {
+		return true
+	}
+
+	value := c.value(r)
+	for _, key := range m.fingerprintKeys[1:] {
+		if hmac.Equal([]byte(recorded), []byte(fingerprintHash(key, c.name, value))) {
+			return true
+		}
+	}
+	for _, key := range append([][]byte{m.cfg.SecretKey}, m.cfg.OldSecretKeys...) {
+		if hmac.Equal([]byte(recorded), []byte(fingerprintHash(key, c.name, value))) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBinding compares the request against the recorded fingerprint and
+// applies the strictest policy among the components that changed.
+func (m *Manager) checkBinding(r *http.Request, sess *Session) *Session {
+	current := m.fingerprint(r)
+	if sess.Fingerprint == nil {
+		sess.Fingerprint = current
+		return sess
+	}
+
+	var changed []string
+	action := BindingIgnore
+	for _, c := range m.cfg.Binding.components() {
+		if c.policy == BindingIgnore {
+			continue
+		}
+		if recorded, ok := sess.Fingerprint[c.name]; ok && !m.fingerprintMatches(recorded, current[c.name], c, r) {
+			changed = append(changed, c.name)
+			if c.policy > action {
+				action = c.policy
+			}
+		}
+	}
+
+	if len(changed) == 0 {
+		sess.Fingerprint = current
+		return sess
+	}
+
+	ctx := r.Context()
+	e := newEvent(sess)
+	e.Err = fmt.Errorf("%w: %s", ErrBindingMismatch, strings.Join(changed, ","))
+	m.emit(m.cfg.Hooks.OnBindingMismatch, ctx, e)
+
+	switch action {
+	case BindingReject:
+		if m.cfg.Store != nil {
+			if err := m.cfg.Store.Delete(sess.ID); err != nil {
+				m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+			}
+		}
+		m.notify(ctx, Change{Kind: ChangeDestroyed, SessionID: sess.ID})
+		sess = m.create(ctx)
+	case BindingRotate:
+		m.rotate(ctx, sess)
+	}
+
+	sess.Fingerprint = current
+	return sess
+}
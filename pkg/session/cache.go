@@ -0,0 +1,80 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithCacheHeaders controls the headers added to every response that
+// carries a session token: Cache-Control "private, no-store" unless the
+// handler set Cache-Control itself, and the request headers carrying the
+// token added to Vary, "Cookie" with the default transport. On in
+// DefaultConfig and DevConfig.
+func WithCacheHeaders(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.CacheHeaders = enabled
+	}
+}
+
+// WriteCacheHeaders adds the headers of WithCacheHeaders to h when the
+// option is on, for adapters that write the session cookie themselves
+func (m *Manager) WriteCacheHeaders(h http.Header) {
+	if !m.cfg.CacheHeaders {
+		return
+	}
+
+	if h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", "private, no-store")
+	}
+
+	fields := varyFields(m.cfg.Transport)
+	if m.cfg.RememberMe != nil {
+		// The remember-me token always travels in a cookie
+		fields = append(fields, "Cookie")
+	}
+
+	var present []string
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" {
+				return
+			}
+			present = append(present, field)
+		}
+	}
+
+	for _, field := range fields {
+		if !containsFold(present, field) {
+			h.Add("Vary", field)
+			present = append(present, field)
+		}
+	}
+}
+
+// varyFields returns the request headers t reads the token from
+func varyFields(t Transport) []string {
+	switch t := t.(type) {
+	case HeaderTransport:
+		return []string{t.header()}
+	case *HeaderTransport:
+		return []string{t.header()}
+	case multiTransport:
+		var fields []string
+		for _, sub := range t {
+			fields = append(fields, varyFields(sub)...)
+		}
+		return fields
+	default:
+		return []string{"Cookie"}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/abmcmanu/sessionx/pkg/store/memory"
+)
+
+func TestCacheHeadersVary(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []session.ConfigOption
+		set  []string
+		want []string
+	}{
+		{"cookie", nil, nil, []string{"Cookie"}},
+		{"header", []session.ConfigOption{session.WithTransport(session.HeaderTransport{Header: "Authorization", Scheme: "Bearer"})}, nil, []string{"Authorization"}},
+		{"both", []session.ConfigOption{session.WithTransport(session.Transports(session.CookieTransport{}, session.HeaderTransport{}))}, nil, []string{"Cookie", "X-Session-Token"}},
+		{"header with remember-me", []session.ConfigOption{session.WithTransport(session.HeaderTransport{}), session.WithRememberMe(session.RememberMe{Store: memory.NewMemoryStore(memory.Options{})})}, nil, []string{"X-Session-Token", "Cookie"}},
+		{"already set", nil, []string{"Accept-Encoding, cookie"}, []string{"Accept-Encoding, cookie"}},
+		{"wildcard", []session.ConfigOption{session.WithTransport(session.HeaderTransport{})}, []string{"*"}, []string{"*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := session.NewManager(session.DevConfig(testKey, tt.opts...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := http.Header{}
+			for _, v := range tt.set {
+				h.Add("Vary", v)
+			}
+			manager.WriteCacheHeaders(h)
+
+			if got := h.Values("Vary"); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Vary = %q, want %q", got, tt.want)
+			}
+			if got := h.Get("Cache-Control"); got != "private, no-store" {
+				t.Fatalf("Cache-Control = %q", got)
+			}
+		})
+	}
+}
+
+func TestCacheHeadersOnSave(t *testing.T) {
+	manager, err := session.NewManager(session.DevConfig(testKey, session.WithTransport(session.HeaderTransport{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := manager.Save(rec, manager.New()); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("X-Session-Token") == "" {
+		t.Fatal("no token in the response")
+	}
+	if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"X-Session-Token"}) {
+		t.Fatalf("Vary = %q, want X-Session-Token", got)
+	}
+}
@@ -3,16 +3,48 @@ package session
 import "time"
 
 type Config struct {
-	CookieName       string
-	SecretKey        []byte
-	MaxAge           time.Duration
+	CookieName    string
+	SecretKey     []byte
+	OldSecretKeys [][]byte
+	MaxAge        time.Duration
+	// MaxSessionAge caps Session.SetMaxAge and is how long RevokeSessionID
+	// keeps an ID revoked. Defaults to MaxAge.
+	MaxSessionAge    time.Duration
 	Path             string
 	Domain           string
 	Secure           bool
 	HttpOnly         bool
 	SameSite         string
+	Partitioned      bool
 	RotationInterval time.Duration
 	Store            Store
+	Transport        Transport
+	Hooks            Hooks
+	ErrorHandler     ErrorHandler
+	Binding          *Binding
+
+	MaxSessionsPerUser int
+	SessionLimitPolicy LimitPolicy
+
+	RevocationStore   RevocationStore
+	RevocationChecker RevocationChecker
+
+	RememberMe *RememberMe
+
+	// Skipper selects requests that bypass session handling, see WithSkipper
+	Skipper Matcher
+
+	// ReadOnly selects requests whose session is never saved or rotated,
+	// see WithReadOnly
+	ReadOnly      Matcher
+	ReadOnlyPanic bool
+
+	// CacheHeaders keeps shared caches from storing responses that carry
+	// a session token, see WithCacheHeaders
+	CacheHeaders bool
+
+	// Notifier tells Watchers about changes made elsewhere, see WithNotifier
+	Notifier Notifier
 }
 
 type ConfigOption func(*Config)
@@ -27,6 +59,7 @@ func DefaultConfig(secretKey []byte, opts ...ConfigOption) Config {
 		HttpOnly:         true,
 		SameSite:         "Lax",
 		RotationInterval: 15 * time.Minute,
+		CacheHeaders:     true,
 	}
 
 	for _, opt := range opts {
@@ -46,6 +79,8 @@ func DevConfig(secretKey []byte, opts ...ConfigOption) Config {
 		HttpOnly:         true,
 		SameSite:         "Lax",
 		RotationInterval: 15 * time.Minute,
+		ReadOnlyPanic:    true,
+		CacheHeaders:     true,
 	}
 
 	for _, opt := range opts {
@@ -55,12 +90,28 @@ func DevConfig(secretKey []byte, opts ...ConfigOption) Config {
 	return cfg
 }
 
+// WithOldSecretKeys keeps sessions encrypted with previous keys readable.
+// New sessions are always encrypted with SecretKey.
+func WithOldSecretKeys(keys ...[]byte) ConfigOption {
+	return func(c *Config) {
+		c.OldSecretKeys = keys
+	}
+}
+
 func WithMaxAge(d time.Duration) ConfigOption {
 	return func(c *Config) {
 		c.MaxAge = d
 	}
 }
 
+// WithMaxSessionAge allows Session.SetMaxAge up to d, e.g. for "stay signed
+// in" sessions longer than MaxAge
+func WithMaxSessionAge(d time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaxSessionAge = d
+	}
+}
+
 func WithCookieName(name string) ConfigOption {
 	return func(c *Config) {
 		c.CookieName = name
@@ -97,6 +148,14 @@ func WithSameSite(sameSite string) ConfigOption {
 	}
 }
 
+// WithPartitioned sets the CHIPS Partitioned attribute so the cookie keeps
+// working in third-party iframes. It requires Secure and SameSite=None.
+func WithPartitioned(partitioned bool) ConfigOption {
+	return func(c *Config) {
+		c.Partitioned = partitioned
+	}
+}
+
 func WithRotationInterval(d time.Duration) ConfigOption {
 	return func(c *Config) {
 		c.RotationInterval = d
@@ -108,3 +167,24 @@ func WithStore(store Store) ConfigOption {
 		c.Store = store
 	}
 }
+
+func WithHooks(hooks Hooks) ConfigOption {
+	return func(c *Config) {
+		c.Hooks = hooks
+	}
+}
+
+func WithErrorHandler(h ErrorHandler) ConfigOption {
+	return func(c *Config) {
+		c.ErrorHandler = h
+	}
+}
+
+// WithMaxSessionsPerUser limits how many sessions SetUser lets a user hold.
+// It requires a store implementing UserIndex.
+func WithMaxSessionsPerUser(n int, policy LimitPolicy) ConfigOption {
+	return func(c *Config) {
+		c.MaxSessionsPerUser = n
+		c.SessionLimitPolicy = policy
+	}
+}
@@ -1 +1,25 @@
 package session
+
+import "context"
+
+// contextKey is unexported so only this package can store a session in a
+// context
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying sess
+func NewContext(ctx context.Context, sess *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, sess)
+}
+
+// FromContext returns the session stored by NewContext, or nil. It works
+// anywhere the request context is passed, not only in HTTP handlers.
+func FromContext(ctx context.Context) *Session {
+	if sess, ok := ctx.Value(contextKey{}).(*Session); ok {
+		return sess
+	}
+	// Sessions stored under the deprecated Key
+	if sess, ok := ctx.Value(Key).(*Session); ok {
+		return sess
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfKey        = "_csrf"
+	csrfSecretSize = 32
+)
+
+// CSRFToken returns a token for forms and templates. Each call masks the
+// per-session secret with a fresh random pad so the token differs on every
+// response (BREACH mitigation); all of them stay valid for the session.
+func (s *Session) CSRFToken() string {
+	secret := s.csrfSecret(true)
+	if secret == nil {
+		return ""
+	}
+
+	pad := make([]byte, csrfSecretSize)
+	if _, err := io.ReadFull(rand.Reader, pad); err != nil {
+		return ""
+	}
+
+	token := make([]byte, 2*csrfSecretSize)
+	copy(token, pad)
+	for i := range secret {
+		token[csrfSecretSize+i] = pad[i] ^ secret[i]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// ValidCSRFToken reports whether token was issued by CSRFToken for this session
+func (s *Session) ValidCSRFToken(token string) bool {
+	secret := s.csrfSecret(false)
+	if secret == nil {
+		return false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 2*csrfSecretSize {
+		return false
+	}
+
+	unmasked := make([]byte, csrfSecretSize)
+	for i := range unmasked {
+		unmasked[i] = raw[i] ^ raw[csrfSecretSize+i]
+	}
+
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
+
+func (s *Session) csrfSecret(create bool) []byte {
+	if encoded, ok := s.Data[csrfKey].(string); ok {
+		if secret, err := base64.RawURLEncoding.DecodeString(encoded); err == nil && len(secret) == csrfSecretSize {
+			return secret
+		}
+	}
+
+	if !create {
+		return nil
+	}
+
+	secret := make([]byte, csrfSecretSize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil
+	}
+	if s.Data == nil {
+		s.Data = map[string]interface{}{}
+	}
+	s.Data[csrfKey] = base64.RawURLEncoding.EncodeToString(secret)
+	return secret
+}
+
+type CSRFConfig struct {
+	HeaderName string
+	FieldName  string
+	// ExemptPaths are matched exactly, or as a prefix when ending in "*"
+	ExemptPaths    []string
+	FailureHandler ErrorHandler
+}
+
+type CSRFOption func(*CSRFConfig)
+
+func DefaultCSRFConfig(opts ...CSRFOption) CSRFConfig {
+	cfg := CSRFConfig{
+		HeaderName:     "X-CSRF-Token",
+		FieldName:      "csrf_token",
+		FailureHandler: csrfFailure,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+func WithCSRFHeaderName(name string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.HeaderName = name
+	}
+}
+
+func WithCSRFFieldName(name string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.FieldName = name
+	}
+}
+
+func WithCSRFExemptPaths(paths ...string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.ExemptPaths = append(c.ExemptPaths, paths...)
+	}
+}
+
+func WithCSRFFailureHandler(h ErrorHandler) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.FailureHandler = h
+	}
+}
+
+// Verify checks the token on unsafe methods. Safe methods and exempt paths
+// always pass.
+func (c CSRFConfig) Verify(r *http.Request, sess *Session) error {
+	if c.Exempt(r.Method, r.URL.Path) {
+		return nil
+	}
+
+	token := r.Header.Get(c.HeaderName)
+	if token == "" && sess != nil {
+		token = r.PostFormValue(c.FieldName)
+	}
+	return c.VerifyToken(sess, token)
+}
+
+// Exempt reports whether a request needs no token
+func (c CSRFConfig) Exempt(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	for _, p := range c.ExemptPaths {
+		if path == p || (strings.HasSuffix(p, "*") && strings.HasPrefix(path, strings.TrimSuffix(p, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyToken checks a token read from the request by the caller, for
+// adapters not built on net/http.
+func (c CSRFConfig) VerifyToken(sess *Session, token string) error {
+	if sess == nil {
+		return newError("CSRF", ErrCSRFTokenInvalid)
+	}
+	if token == "" {
+		return newError("CSRF", ErrCSRFTokenMissing)
+	}
+	if !sess.ValidCSRFToken(token) {
+		return newError("CSRF", ErrCSRFTokenInvalid)
+	}
+	return nil
+}
+
+// CSRF rejects unsafe requests without a valid token. It must be installed
+// inside Manager.Middleware.
+func CSRF(opts ...CSRFOption) func(http.Handler) http.Handler {
+	cfg := DefaultCSRFConfig(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := cfg.Verify(r, Get(r)); err != nil {
+				cfg.FailureHandler(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func csrfFailure(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
@@ -0,0 +1,120 @@
+package session_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newSession(t *testing.T) *session.Session {
+	t.Helper()
+
+	manager, err := session.NewManager(session.DevConfig(testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager.New()
+}
+
+func TestCSRFTokenRoundTrip(t *testing.T) {
+	sess := newSession(t)
+
+	first := sess.CSRFToken()
+	second := sess.CSRFToken()
+	if first == second {
+		t.Fatal("tokens are not masked: two calls returned the same token")
+	}
+	for _, token := range []string{first, second} {
+		if !sess.ValidCSRFToken(token) {
+			t.Fatalf("token %q rejected by its own session", token)
+		}
+	}
+}
+
+func TestCSRFTokenTampered(t *testing.T) {
+	sess := newSession(t)
+	token := sess.CSRFToken()
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(raw)
+
+	for name, token := range map[string]string{
+		"flipped bit": tampered,
+		"truncated":   token[:len(token)-4],
+		"not base64":  "!" + token[1:],
+		"empty":       "",
+	} {
+		if sess.ValidCSRFToken(token) {
+			t.Errorf("%s token accepted", name)
+		}
+	}
+}
+
+func TestCSRFTokenOtherSession(t *testing.T) {
+	token := newSession(t).CSRFToken()
+	other := newSession(t)
+	other.CSRFToken()
+
+	if other.ValidCSRFToken(token) {
+		t.Fatal("token of another session accepted")
+	}
+}
+
+func TestCSRFTokenWithoutSecret(t *testing.T) {
+	token := newSession(t).CSRFToken()
+
+	if newSession(t).ValidCSRFToken(token) {
+		t.Fatal("token accepted by a session without a CSRF secret")
+	}
+}
+
+func TestCSRFVerify(t *testing.T) {
+	sess := newSession(t)
+	cfg := session.DefaultCSRFConfig(session.WithCSRFExemptPaths("/webhooks/*"))
+
+	form := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+	header := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		return r
+	}
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		want error
+	}{
+		{"form field", form(sess.CSRFToken()), nil},
+		{"header", header(sess.CSRFToken()), nil},
+		{"safe method", httptest.NewRequest(http.MethodGet, "/form", nil), nil},
+		{"exempt path", httptest.NewRequest(http.MethodPost, "/webhooks/stripe", nil), nil},
+		{"missing", httptest.NewRequest(http.MethodPost, "/form", nil), session.ErrCSRFTokenMissing},
+		{"invalid", header("bm90IGEgdG9rZW4"), session.ErrCSRFTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		err := cfg.Verify(tt.r, sess)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -3,16 +3,33 @@ package session
 import (
 	"errors"
 	"fmt"
+	"log"
+	"net/http"
 )
 
 var (
 	ErrInvalidSession   = errors.New("invalid or corrupted session")
 	ErrSessionExpired   = errors.New("session has expired")
 	ErrInvalidSecretKey = errors.New("secret key must be 16, 24, or 32 bytes")
+	ErrInvalidConfig    = errors.New("invalid session config")
 	ErrDecryptionFailed = errors.New("failed to decrypt session data")
 	ErrMarshalFailed    = errors.New("failed to marshal session data")
 	ErrUnmarshalFailed  = errors.New("failed to unmarshal session data")
 	ErrEncryptionFailed = errors.New("failed to encrypt session data")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrCookieTooLarge   = errors.New("session cookie exceeds 4096 bytes")
+	ErrCSRFTokenMissing = errors.New("csrf token missing")
+	ErrCSRFTokenInvalid = errors.New("csrf token invalid")
+	ErrBindingMismatch  = errors.New("client fingerprint changed")
+	ErrSessionReadOnly  = errors.New("session is read-only")
+
+	ErrUserIndexUnsupported = errors.New("store does not index sessions by user")
+	ErrSessionLimitReached  = errors.New("maximum number of sessions reached")
+
+	ErrRevocationUnsupported = errors.New("no revocation store configured")
+	ErrRememberUnsupported   = errors.New("remember-me is not configured")
+	ErrRememberTokenTheft    = errors.New("remember-me token reused after rotation")
+	ErrNoUser                = errors.New("session has no user")
 )
 
 type SessionError struct {
@@ -37,4 +54,36 @@ func newError(op string, err error) error {
 		return nil
 	}
 	return &SessionError{Op: op, Err: err}
-}
\ No newline at end of file
+}
+
+// ErrorHandler is invoked by the middleware when a session cannot be loaded
+// or saved. If it writes a response the request is not passed on (load) or
+// the handler's own response is discarded (save).
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler logs the error and lets the request continue with a
+// fresh session.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("sessionx: %s %s: %v", r.Method, r.URL.Path, err)
+}
+
+// FailClosed logs the error and answers with status, typically
+// http.StatusInternalServerError or http.StatusServiceUnavailable. A cookie
+// that cannot be decrypted or decoded, e.g. after a key rotation without
+// WithOldSecretKeys, is only logged: the request continues with a fresh
+// session whose cookie replaces the bad one.
+func FailClosed(status int) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		DefaultErrorHandler(w, r, err)
+		if isClientError(err) {
+			return
+		}
+		http.Error(w, http.StatusText(status), status)
+	}
+}
+
+// isClientError reports whether err comes from the token the client sent
+// rather than from the server side
+func isClientError(err error) bool {
+	return errors.Is(err, ErrDecryptionFailed) || errors.Is(err, ErrInvalidSession) || errors.Is(err, ErrUnmarshalFailed)
+}
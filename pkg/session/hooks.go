@@ -0,0 +1,53 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Event carries the session metadata passed to a Hook. Session values are
+// deliberately left out so hooks can be wired straight into audit logs.
+type Event struct {
+	SessionID  string
+	PreviousID string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	RotatedAt  time.Time
+	Err        error
+}
+
+type Hook func(ctx context.Context, e Event)
+
+// Hooks lets callers observe the Manager. Nil callbacks are skipped.
+type Hooks struct {
+	OnCreate         Hook
+	OnLoad           Hook
+	OnSave           Hook
+	OnRotate         Hook
+	OnExpire         Hook
+	OnDestroy        Hook
+	OnDecryptFailure Hook
+	OnStoreError     Hook
+	OnRevoke         Hook
+	// OnRememberTheft fires when a rotated remember-me token is replayed;
+	// all of the user's tokens and sessions are revoked.
+	OnRememberTheft Hook
+	// OnBindingMismatch fires when a Binding component changed; Err names
+	// the components.
+	OnBindingMismatch Hook
+}
+
+func newEvent(sess *Session) Event {
+	return Event{
+		SessionID: sess.ID,
+		CreatedAt: sess.CreatedAt,
+		UpdatedAt: sess.UpdatedAt,
+		RotatedAt: sess.RotatedAt,
+	}
+}
+
+func (m *Manager) emit(hook Hook, ctx context.Context, e Event) {
+	if hook != nil {
+		hook(ctx, e)
+	}
+}
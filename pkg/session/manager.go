@@ -1,46 +1,53 @@
 package session
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/crypto"
 )
 
+// maxCookieSize is the smallest per-cookie limit browsers are required to
+// support (RFC 6265, section 6.1).
+const maxCookieSize = 4096
+
 type Manager struct {
-	cfg Config
+	cfg     Config
+	keyring *crypto.Keyring
+	// fingerprintKeys are derived from SecretKey, then OldSecretKeys
+	fingerprintKeys [][]byte
 }
 
 func NewManager(cfg Config) (*Manager, error) {
-	keyLen := len(cfg.SecretKey)
-	if keyLen != 16 && keyLen != 24 && keyLen != 32 {
-		return nil, newError("NewManager", ErrInvalidSecretKey)
+	if err := cfg.Validate(); err != nil {
+		return nil, newError("NewManager", err)
 	}
 
-	return &Manager{cfg: cfg}, nil
-}
-
-func (m *Manager) encrypt(data []byte) (string, error) {
-	block, err := aes.NewCipher(m.cfg.SecretKey)
+	keyring, err := crypto.NewKeyring(cfg.SecretKey, cfg.OldSecretKeys...)
 	if err != nil {
-		return "", newError("encrypt", ErrEncryptionFailed)
+		return nil, newError("NewManager", ErrInvalidSecretKey)
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", newError("encrypt", ErrEncryptionFailed)
+	m := &Manager{cfg: cfg, keyring: keyring}
+	for _, key := range append([][]byte{cfg.SecretKey}, cfg.OldSecretKeys...) {
+		m.fingerprintKeys = append(m.fingerprintKeys, fingerprintKey(key))
 	}
+	return m, nil
+}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+func (m *Manager) encrypt(data []byte) (string, error) {
+	encrypted, err := m.keyring.Encrypt(data)
+	if err != nil {
 		return "", newError("encrypt", ErrEncryptionFailed)
 	}
 
-	encrypted := gcm.Seal(nonce, nonce, data, nil)
 	return base64.RawStdEncoding.EncodeToString(encrypted), nil
 }
 
@@ -50,23 +57,10 @@ func (m *Manager) decrypt(encoded string) ([]byte, error) {
 		return nil, newError("decrypt", ErrInvalidSession)
 	}
 
-	block, err := aes.NewCipher(m.cfg.SecretKey)
-	if err != nil {
-		return nil, newError("decrypt", ErrDecryptionFailed)
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, newError("decrypt", ErrDecryptionFailed)
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(raw) < nonceSize {
+	decrypted, err := m.keyring.Decrypt(raw)
+	if errors.Is(err, crypto.ErrCiphertextTooShort) {
 		return nil, newError("decrypt", ErrInvalidSession)
 	}
-
-	nonce, ciphertext := raw[:nonceSize], raw[nonceSize:]
-	decrypted, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return nil, newError("decrypt", ErrDecryptionFailed)
 	}
@@ -74,114 +68,440 @@ func (m *Manager) decrypt(encoded string) ([]byte, error) {
 	return decrypted, nil
 }
 
+// Load returns the session for r. A fresh session is returned when there is
+// none; if the cookie cannot be decrypted or the store fails, the fresh
+// session comes back together with the error.
 func (m *Manager) Load(r *http.Request) (*Session, error) {
-	c, err := r.Cookie(m.cfg.CookieName)
-	if err != nil {
-		return m.New(), nil
+	readOnly := m.cfg.ReadOnly != nil && m.cfg.ReadOnly(r)
+
+	sess, err := m.load(r, !readOnly)
+	if err == nil && sess.fresh && m.cfg.RememberMe != nil {
+		m.restoreRemembered(r, sess)
+	}
+	if m.cfg.Binding != nil {
+		sess = m.checkBinding(r, sess)
+	}
+	sess.transport = m.transportFor(r)
+	sess.readOnlyPanic = m.cfg.ReadOnlyPanic
+	if readOnly {
+		sess.SetReadOnly()
+	}
+	return sess, err
+}
+
+func (m *Manager) load(r *http.Request, rotate bool) (*Session, error) {
+	token, _ := m.transportFor(r).Read(r, m.cfg.CookieName)
+	return m.loadValue(r.Context(), token, rotate)
+}
+
+// LoadValue returns the session for a raw token as sent by the client, for
+// servers not built on net/http. An empty token yields a fresh session.
+// Binding and RememberMe need the request and are only applied by Load.
+func (m *Manager) LoadValue(ctx context.Context, token string) (*Session, error) {
+	sess, err := m.loadValue(ctx, token, true)
+	sess.readOnlyPanic = m.cfg.ReadOnlyPanic
+	return sess, err
+}
+
+func (m *Manager) loadValue(ctx context.Context, token string, rotate bool) (*Session, error) {
+	if token == "" {
+		return m.create(ctx), nil
 	}
 
 	var sess *Session
 
 	if m.cfg.Store != nil {
-		sess, err = m.cfg.Store.Load(c.Value)
+		if !validID(token) {
+			// Never looked up: the store shares its keyspace with other records
+			return m.create(ctx), nil
+		}
+		var err error
+		sess, err = m.cfg.Store.Load(token)
 		if err != nil {
-			return m.New(), nil
+			if errors.Is(err, ErrSessionNotFound) {
+				return m.create(ctx), nil
+			}
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: token, Err: err})
+			return m.create(ctx), newError("Load", err)
+		}
+		if sess.RotatedTo != "" {
+			return m.create(ctx), nil
 		}
 	} else {
-		decrypted, err := m.decrypt(c.Value)
+		decrypted, err := m.decrypt(token)
 		if err != nil {
-			return m.New(), nil
+			m.emit(m.cfg.Hooks.OnDecryptFailure, ctx, Event{Err: err})
+			return m.create(ctx), err
 		}
 
 		var s Session
 		if err := json.Unmarshal(decrypted, &s); err != nil {
-			return m.New(), nil
+			err = newError("Load", ErrUnmarshalFailed)
+			m.emit(m.cfg.Hooks.OnDecryptFailure, ctx, Event{Err: err})
+			return m.create(ctx), err
 		}
 		sess = &s
 	}
 
-	if m.cfg.MaxAge > 0 && time.Since(sess.UpdatedAt) > m.cfg.MaxAge {
-		return m.New(), nil
+	if maxAge := m.maxAge(sess); maxAge > 0 && time.Since(sess.UpdatedAt) > maxAge {
+		m.emit(m.cfg.Hooks.OnExpire, ctx, newEvent(sess))
+		return m.create(ctx), nil
+	}
+
+	revoked, err := m.isRevoked(ctx, sess)
+	if err != nil {
+		m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+		return m.create(ctx), newError("Load", err)
+	}
+	if revoked {
+		m.emit(m.cfg.Hooks.OnRevoke, ctx, newEvent(sess))
+		return m.create(ctx), nil
 	}
 
-	if m.cfg.RotationInterval > 0 && time.Since(sess.RotatedAt) > m.cfg.RotationInterval {
-		m.Rotate(sess)
+	if rotate && m.cfg.RotationInterval > 0 && time.Since(sess.RotatedAt) > m.cfg.RotationInterval {
+		m.rotate(ctx, sess)
 	}
 
+	m.emit(m.cfg.Hooks.OnLoad, ctx, newEvent(sess))
 	return sess, nil
 }
 
 func (m *Manager) New() *Session {
+	return m.create(context.Background())
+}
+
+func (m *Manager) create(ctx context.Context) *Session {
 	now := time.Now()
-	return &Session{
+	sess := &Session{
 		ID:        m.newID(),
 		Data:      map[string]interface{}{},
 		CreatedAt: now,
 		UpdatedAt: now,
 		RotatedAt: now,
+		fresh:     true,
 	}
+	m.stampEpoch(ctx, sess, "")
+
+	m.emit(m.cfg.Hooks.OnCreate, ctx, newEvent(sess))
+	return sess
 }
 
 func (m *Manager) Save(w http.ResponseWriter, sess *Session) error {
+	return m.SaveContext(context.Background(), w, sess)
+}
+
+// SaveContext is Save with the context handed to Hooks
+func (m *Manager) SaveContext(ctx context.Context, w http.ResponseWriter, sess *Session) error {
+	value, err := m.saveValue(ctx, "Save", sess)
+	if err != nil {
+		return err
+	}
+
+	transport := sess.transport
+	if transport == nil {
+		transport = m.defaultTransport()
+	}
+	transport.Write(w, m.CookieFor(sess, value))
+	m.WriteCacheHeaders(w.Header())
+	for _, c := range sess.pendingCookies {
+		setCookie(w, c)
+	}
+	sess.pendingCookies = nil
+
+	m.emit(m.cfg.Hooks.OnSave, ctx, newEvent(sess))
+	return nil
+}
+
+// SaveValue persists sess and returns the token to send to the client,
+// see Cookie. It is the net/http independent half of Save.
+func (m *Manager) SaveValue(ctx context.Context, sess *Session) (string, error) {
+	value, err := m.saveValue(ctx, "SaveValue", sess)
+	if err != nil {
+		return "", err
+	}
+
+	m.emit(m.cfg.Hooks.OnSave, ctx, newEvent(sess))
+	return value, nil
+}
+
+func (m *Manager) saveValue(ctx context.Context, op string, sess *Session) (string, error) {
+	if sess.readOnly && !sess.bookkeepingOnly() {
+		return "", newError(op, ErrSessionReadOnly)
+	}
+
 	sess.UpdatedAt = time.Now()
+	// The store keeps the record as long as the session asks
+	if limit := m.maxSessionAge(); sess.MaxAge > limit {
+		sess.MaxAge = limit
+	}
 
 	var cookieValue string
 
 	if m.cfg.Store != nil {
 		if err := m.cfg.Store.Save(sess); err != nil {
-			return err
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+			return "", newError(op, err)
+		}
+
+		// The record under the pre-rotation ID is no longer reachable
+		if sess.previousID != "" {
+			if err := m.retire(sess.previousID, sess); err != nil {
+				m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.previousID, Err: err})
+			}
 		}
 		cookieValue = sess.ID
 	} else {
 		raw, err := json.Marshal(sess)
 		if err != nil {
-			return newError("Save", ErrMarshalFailed)
+			return "", newError(op, ErrMarshalFailed)
 		}
 
 		encrypted, err := m.encrypt(raw)
 		if err != nil {
-			return err
+			return "", err
 		}
 		cookieValue = encrypted
 	}
 
-	cookie := &http.Cookie{
-		Name:     m.cfg.CookieName,
-		Value:    cookieValue,
-		Path:     m.cfg.Path,
-		Domain:   m.cfg.Domain,
-		HttpOnly: m.cfg.HttpOnly,
-		Secure:   m.cfg.Secure,
-		SameSite: parseSameSite(m.cfg.SameSite),
-		MaxAge:   int(m.cfg.MaxAge.Seconds()),
+	if len(m.CookieFor(sess, cookieValue).String()) > maxCookieSize {
+		return "", newError(op, ErrCookieTooLarge)
 	}
 
-	w.Header().Add("Set-Cookie", cookie.String())
-	return nil
+	if sess.previousID != "" {
+		m.notify(ctx, Change{Kind: ChangeRotated, SessionID: sess.previousID, NewID: sess.ID})
+		sess.previousID = ""
+	}
+	return cookieValue, nil
 }
 
+// Destroy expires the session cookie and deletes the store record. The
+// session attached to r is marked destroyed so the middleware does not save
+// it again at the end of the request.
 func (m *Manager) Destroy(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	sess := Get(r)
+	if sess != nil {
+		sess.destroyed = true
+	}
+
+	token, transport, hasToken := m.readToken(r)
+	transport.Write(w, m.cookie("", -1))
+	m.WriteCacheHeaders(w.Header())
+
+	if !hasToken {
+		token = ""
+	}
+	// The session goes first: a remember-me store failure must not leave
+	// it alive
+	err := m.destroyValue(ctx, "Destroy", token, sess)
+
+	if rerr := m.forgetRemembered(w, r, sess); rerr != nil {
+		m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{Err: rerr})
+		err = errors.Join(err, newError("Destroy", rerr))
+	}
+	return err
+}
+
+// DestroyValue deletes the store record behind token and sess, either of
+// which may be empty, and marks sess destroyed. The caller expires the
+// client's token, see ExpiredCookie.
+func (m *Manager) DestroyValue(ctx context.Context, token string, sess *Session) error {
+	if sess != nil {
+		sess.destroyed = true
+	}
+	return m.destroyValue(ctx, "DestroyValue", token, sess)
+}
+
+func (m *Manager) destroyValue(ctx context.Context, op, token string, sess *Session) error {
+	var e Event
+	if sess != nil {
+		e = newEvent(sess)
+	}
+
 	if m.cfg.Store != nil {
-		c, err := r.Cookie(m.cfg.CookieName)
-		if err == nil {
-			_ = m.cfg.Store.Delete(c.Value)
+		if validID(token) {
+			if e.SessionID == "" {
+				e.SessionID = token
+			}
+			if err := m.cfg.Store.Delete(token); err != nil {
+				m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: token, Err: err})
+				return newError(op, err)
+			}
+		}
+		if sess != nil {
+			if err := m.cfg.Store.Delete(sess.ID); err != nil {
+				m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+				return newError(op, err)
+			}
+		}
+	}
+
+	// Without a store the cookie stays valid until MaxAge wherever it was
+	// copied; the revocation list closes that gap.
+	if m.cfg.Store == nil && m.cfg.RevocationStore != nil && sess != nil {
+		if err := m.cfg.RevocationStore.RevokeID(ctx, sess.ID, m.maxAge(sess)); err != nil {
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+			return newError(op, err)
 		}
 	}
 
-	expired := &http.Cookie{
-		Name:     m.cfg.CookieName,
-		Value:    "",
-		MaxAge:   -1,
-		Path:     m.cfg.Path,
-		HttpOnly: true,
+	if sess != nil {
+		m.notify(ctx, Change{Kind: ChangeDestroyed, SessionID: sess.ID})
+	}
+	if token != "" && (sess == nil || token != sess.ID) && m.cfg.Store != nil {
+		m.notify(ctx, Change{Kind: ChangeDestroyed, SessionID: token})
 	}
-	http.SetCookie(w, expired)
+
+	m.emit(m.cfg.Hooks.OnDestroy, ctx, e)
 	return nil
 }
 
+// Cookie returns the session cookie carrying value with the configured
+// attributes, for adapters that write cookies themselves.
+func (m *Manager) Cookie(value string) *http.Cookie {
+	return m.cookie(value, int(m.cfg.MaxAge.Seconds()))
+}
+
+// CookieFor is Cookie with the lifetime set by Session.SetMaxAge
+func (m *Manager) CookieFor(sess *Session, value string) *http.Cookie {
+	return m.cookie(value, int(m.maxAge(sess).Seconds()))
+}
+
+func (m *Manager) maxAge(sess *Session) time.Duration {
+	if sess.MaxAge > 0 {
+		return min(sess.MaxAge, m.maxSessionAge())
+	}
+	return m.cfg.MaxAge
+}
+
+// maxSessionAge is the longest any session may live, and so how long a
+// revoked ID must stay revoked
+func (m *Manager) maxSessionAge() time.Duration {
+	return max(m.cfg.MaxSessionAge, m.cfg.MaxAge)
+}
+
+// ExpiredCookie returns the cookie that clears the session on the client
+func (m *Manager) ExpiredCookie() *http.Cookie {
+	return m.cookie("", -1)
+}
+
+func (m *Manager) CookieName() string {
+	return m.cfg.CookieName
+}
+
+// cookie builds the session cookie so Save and Destroy always agree on
+// its attributes; browsers only drop a cookie whose Path and Domain match.
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return m.namedCookie(m.cfg.CookieName, value, maxAge)
+}
+
+func (m *Manager) namedCookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:        name,
+		Value:       value,
+		Path:        m.cfg.Path,
+		Domain:      m.cfg.Domain,
+		HttpOnly:    m.cfg.HttpOnly,
+		Secure:      m.cfg.Secure,
+		SameSite:    parseSameSite(m.cfg.SameSite),
+		Partitioned: m.cfg.Partitioned,
+		MaxAge:      maxAge,
+	}
+}
+
 func (m *Manager) Rotate(sess *Session) {
+	m.rotate(context.Background(), sess)
+}
+
+func (m *Manager) rotate(ctx context.Context, sess *Session) {
+	previous := sess.ID
+	if sess.previousID == "" {
+		sess.previousID = previous
+	}
 	sess.ID = m.newID()
 	sess.RotatedAt = time.Now()
+
+	e := newEvent(sess)
+	e.PreviousID = previous
+	m.emit(m.cfg.Hooks.OnRotate, ctx, e)
+}
+
+type RenewOptions struct {
+	// ClearData drops all session values, flashes included
+	ClearData bool
+}
+
+// Renew issues a fresh session ID, replaces the previous store record with
+// a tombstone and rewrites the cookie. Call it on login or privilege
+// changes to prevent session fixation. A read-only session becomes writable.
+func (m *Manager) Renew(w http.ResponseWriter, r *http.Request, sess *Session, opts RenewOptions) error {
+	ctx := r.Context()
+
+	token, _, _ := m.readToken(r)
+	if err := m.renew(ctx, "Renew", token, sess, opts); err != nil {
+		return err
+	}
+
+	return m.SaveContext(ctx, w, sess)
+}
+
+// RenewValue is Renew without the save; token is the one the client sent.
+// The caller saves sess afterwards with SaveValue.
+func (m *Manager) RenewValue(ctx context.Context, token string, sess *Session, opts RenewOptions) error {
+	return m.renew(ctx, "RenewValue", token, sess, opts)
+}
+
+func (m *Manager) renew(ctx context.Context, op, token string, sess *Session, opts RenewOptions) error {
+	// A login is always a write; the save below must not be refused after
+	// the previous record is gone
+	sess.readOnly = false
+
+	old := sess.ID
+	m.rotate(ctx, sess)
+	sess.CreatedAt = sess.RotatedAt
+
+	if m.cfg.Store != nil {
+		if err := m.retire(old, sess); err != nil {
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: old, Err: err})
+			return newError(op, err)
+		}
+		if validID(token) && token != old {
+			if err := m.cfg.Store.Delete(token); err != nil {
+				m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: token, Err: err})
+				return newError(op, err)
+			}
+		}
+	}
+
+	if opts.ClearData {
+		sess.Data = map[string]interface{}{}
+	}
+	return nil
+}
+
+// tombstoneTTL is how long a tombstone outlives the rotation, enough for
+// Watchers revalidating at the default interval
+const tombstoneTTL = 5 * time.Minute
+
+// retire replaces the record of a rotated-away ID with a tombstone naming
+// its successor. Load refuses it; Watchers that missed the notification
+// follow it. Stores keep it for its MaxAge, see tombstoneTTL.
+func (m *Manager) retire(id string, sess *Session) error {
+	if sess.fresh {
+		// The client never held id, nothing can follow it
+		return m.cfg.Store.Delete(id)
+	}
+
+	now := time.Now()
+	return m.cfg.Store.Save(&Session{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		RotatedAt: now,
+		MaxAge:    min(tombstoneTTL, m.maxAge(sess)),
+		RotatedTo: sess.ID,
+	})
 }
 
 func (m *Manager) newID() string {
@@ -190,6 +510,52 @@ func (m *Manager) newID() string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
+// validID reports whether s has the shape of an ID made by newID. A token
+// from the client is checked before it reaches the store.
+func validID(s string) bool {
+	return randomShape(s, 16)
+}
+
+// randomShape reports whether s could be n random bytes encoded as newID
+// and randomToken do
+func randomShape(s string, n int) bool {
+	if len(s) != base64.RawURLEncoding.EncodedLen(n) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleError passes err to the configured ErrorHandler
+func (m *Manager) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	if m.cfg.ErrorHandler != nil {
+		m.cfg.ErrorHandler(w, r, err)
+		return
+	}
+	DefaultErrorHandler(w, r, err)
+}
+
+// setCookie writes cookie, replacing any Set-Cookie header already queued
+// for the same name so a session saved twice in one request emits it once.
+func setCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	h := w.Header()
+	prefix := cookie.Name + "="
+
+	var kept []string
+	for _, v := range h.Values("Set-Cookie") {
+		if !strings.HasPrefix(v, prefix) {
+			kept = append(kept, v)
+		}
+	}
+
+	h["Set-Cookie"] = append(kept, cookie.String())
+}
+
 func parseSameSite(s string) http.SameSite {
 	switch s {
 	case "Strict":
@@ -0,0 +1,141 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/abmcmanu/sessionx/pkg/store/memory"
+)
+
+// keyStore records the keys it is asked for
+type keyStore struct {
+	keys []string
+}
+
+func (s *keyStore) Load(id string) (*session.Session, error) {
+	s.keys = append(s.keys, id)
+	return nil, session.ErrSessionNotFound
+}
+
+func (s *keyStore) Save(sess *session.Session) error { return nil }
+
+func (s *keyStore) Delete(id string) error {
+	s.keys = append(s.keys, id)
+	return nil
+}
+
+func TestMalformedTokenNeverReachesStore(t *testing.T) {
+	store := &keyStore{}
+	manager, err := session.NewManager(session.DevConfig(testKey, session.WithStore(store)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"epoch", "user:alice", "revoked:AAAAAAAAAAAAAAAAAAAAAA", "AAAAAAAAAAAAAAAAAAAAA!"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: manager.CookieName(), Value: token})
+
+		sess, err := manager.Load(r)
+		if err != nil {
+			t.Fatalf("Load(%q): %v", token, err)
+		}
+		if err := manager.Renew(httptest.NewRecorder(), r, sess, session.RenewOptions{}); err != nil {
+			t.Fatalf("Renew(%q): %v", token, err)
+		}
+		if err := manager.Destroy(httptest.NewRecorder(), r); err != nil {
+			t.Fatalf("Destroy(%q): %v", token, err)
+		}
+	}
+
+	for _, key := range store.keys {
+		if len(key) != 22 {
+			t.Errorf("store asked for %q", key)
+		}
+	}
+}
+
+// ttlStore records the TTL of revoked IDs
+type ttlStore struct {
+	ttl time.Duration
+}
+
+func (s *ttlStore) RevokeID(ctx context.Context, id string, ttl time.Duration) error {
+	s.ttl = ttl
+	return nil
+}
+
+func (s *ttlStore) IsIDRevoked(ctx context.Context, id string) (bool, error) { return false, nil }
+func (s *ttlStore) Epoch(ctx context.Context, userID string) (uint64, error) { return 0, nil }
+func (s *ttlStore) IncrEpoch(ctx context.Context, userID string) error       { return nil }
+
+func TestMaxSessionAge(t *testing.T) {
+	revocations := &ttlStore{}
+	manager, err := session.NewManager(session.DevConfig(testKey,
+		session.WithMaxAge(time.Hour),
+		session.WithMaxSessionAge(30*24*time.Hour),
+		session.WithRevocationStore(revocations),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new manager, as after a restart, revokes for the configured maximum
+	if err := manager.RevokeSessionID(context.Background(), "AAAAAAAAAAAAAAAAAAAAAA"); err != nil {
+		t.Fatal(err)
+	}
+	if revocations.ttl != 30*24*time.Hour {
+		t.Fatalf("revocation TTL = %v, want 720h", revocations.ttl)
+	}
+
+	sess := manager.New()
+	sess.SetMaxAge(365 * 24 * time.Hour)
+	rec := httptest.NewRecorder()
+	if err := manager.Save(rec, sess); err != nil {
+		t.Fatal(err)
+	}
+	if sess.MaxAge != 30*24*time.Hour {
+		t.Fatalf("MaxAge = %v after save, want it capped at 720h", sess.MaxAge)
+	}
+	if c := rec.Result().Cookies()[0]; c.MaxAge != 30*24*60*60 {
+		t.Fatalf("cookie MaxAge = %d, want 720h", c.MaxAge)
+	}
+}
+
+func TestRenewLeavesShortTombstone(t *testing.T) {
+	store := memory.NewMemoryStore(memory.Options{})
+	manager, err := session.NewManager(session.DevConfig(testKey, session.WithStore(store)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := manager.Save(rec, manager.New()); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(rec.Result().Cookies()[0])
+
+	sess, err := manager.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := sess.ID
+	if err := manager.Renew(httptest.NewRecorder(), r, sess, session.RenewOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tombstone, err := store.Load(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstone.RotatedTo != sess.ID {
+		t.Fatalf("tombstone points to %q, want %q", tombstone.RotatedTo, sess.ID)
+	}
+	if tombstone.MaxAge <= 0 || tombstone.MaxAge > 5*time.Minute {
+		t.Fatalf("tombstone MaxAge = %v, want at most 5m", tombstone.MaxAge)
+	}
+}
@@ -1,66 +1,264 @@
 package session
 
 import (
-	"context"
+	"bufio"
+	"net"
 	"net/http"
 )
 
+// Deprecated: use NewContext and FromContext
 type ContextKey string
 
+// Deprecated: use NewContext and FromContext. FromContext still finds
+// sessions stored under Key.
 var Key ContextKey = "sessionx"
 
 func (m *Manager) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sess, _ := m.Load(r)
+		if m.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		ctx := context.WithValue(r.Context(), Key, sess)
-		r = r.WithContext(ctx)
+		sess, r, ok := m.Begin(w, r)
+		if !ok {
+			return
+		}
 
 		wrapped := &responseWriterWrapper{
 			ResponseWriter: w,
-			session:        sess,
-			manager:        m,
+			saver:          m.NewSaver(w, r, sess),
 		}
 
-		next.ServeHTTP(wrapped, r)
+		next.ServeHTTP(wrapped.expose(), r)
 
-		wrapped.ensureSaved()
+		wrapped.saver.Save()
 	})
 }
 
-type responseWriterWrapper struct {
-	http.ResponseWriter
+// Begin loads the session of r and returns r with the session in its
+// context. When loading fails and the ErrorHandler answers the request
+// itself, ok is false and the request must not be passed on. Adapters use
+// it together with Saver.
+func (m *Manager) Begin(w http.ResponseWriter, r *http.Request) (sess *Session, req *http.Request, ok bool) {
+	sess, err := m.Load(r)
+	if err != nil {
+		ew := &errorWriter{ResponseWriter: w}
+		m.HandleError(ew, r, err)
+		if ew.written {
+			return sess, r, false
+		}
+	}
+
+	return sess, r.WithContext(NewContext(r.Context(), sess)), true
+}
+
+// Saver saves a session once, right before the response commits its
+// headers. Adapters call Save from every method that commits them.
+type Saver struct {
+	w       http.ResponseWriter
+	request *http.Request
 	session *Session
 	manager *Manager
 	saved   bool
+	aborted bool
+}
+
+// NewSaver returns a Saver writing to w, which must be the writer below
+// any adapter wrapper
+func (m *Manager) NewSaver(w http.ResponseWriter, r *http.Request, sess *Session) *Saver {
+	return &Saver{w: w, request: r, session: sess, manager: m}
+}
+
+// Save saves the session unless it already ran or the session opted out,
+// see Session.AutoSave. When saving fails and the error handler answers
+// the request itself, Aborted reports true.
+func (s *Saver) Save() {
+	if s.saved {
+		return
+	}
+	s.saved = true
+
+	if s.session.readOnly && !s.session.destroyed && s.session.modified() {
+		err := newError("Save", ErrSessionReadOnly)
+		if s.session.readOnlyPanic {
+			panic(err)
+		}
+		ew := &errorWriter{ResponseWriter: s.w}
+		s.manager.HandleError(ew, s.request, err)
+		s.aborted = ew.written
+		return
+	}
+
+	if !s.session.AutoSave() {
+		return
+	}
+
+	if err := s.manager.SaveContext(s.request.Context(), s.w, s.session); err != nil {
+		ew := &errorWriter{ResponseWriter: s.w}
+		s.manager.HandleError(ew, s.request, err)
+		s.aborted = ew.written
+	}
+}
+
+// Aborted reports whether the rest of the handler's response must be
+// dropped because the error handler already answered
+func (s *Saver) Aborted() bool {
+	return s.aborted
+}
+
+type responseWriterWrapper struct {
+	http.ResponseWriter
+	saver *Saver
 }
 
 func (rw *responseWriterWrapper) WriteHeader(status int) {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return
 	}
 	rw.ResponseWriter.WriteHeader(status)
 }
 
 func (rw *responseWriterWrapper) Write(b []byte) (int, error) {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return len(b), nil
 	}
 	return rw.ResponseWriter.Write(b)
 }
 
-func (rw *responseWriterWrapper) ensureSaved() {
-	if !rw.saved {
-		_ = rw.manager.Save(rw.ResponseWriter, rw.session)
-		rw.saved = true
+// flush saves the session first since flushing commits the headers
+func (rw *responseWriterWrapper) flush() {
+	rw.saver.Save()
+	if rw.saver.Aborted() {
+		return
 	}
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
 }
 
-func Get(r *http.Request) *Session {
-	if v := r.Context().Value(Key); v != nil {
-		return v.(*Session)
+// hijack saves the session before handing over the connection, e.g. for
+// WebSocket upgrades. The Set-Cookie header is only sent if the caller
+// writes the queued headers itself.
+func (rw *responseWriterWrapper) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.saver.Save()
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+func (rw *responseWriterWrapper) push(target string, opts *http.PushOptions) error {
+	return rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type flusher struct{ rw *responseWriterWrapper }
+
+func (f flusher) Flush() { f.rw.flush() }
+
+type hijacker struct{ rw *responseWriterWrapper }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.rw.hijack() }
+
+type pusher struct{ rw *responseWriterWrapper }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error { return p.rw.push(target, opts) }
+
+// expose returns rw with exactly the optional interfaces of the underlying
+// writer, so handlers detecting http.Flusher or http.Hijacker by type
+// assertion see what is really supported
+func (rw *responseWriterWrapper) expose() http.ResponseWriter {
+	f := supports(rw.ResponseWriter, func(w http.ResponseWriter) bool {
+		_, flush := w.(http.Flusher)
+		_, flushError := w.(interface{ FlushError() error })
+		return flush || flushError
+	})
+	h := supports(rw.ResponseWriter, func(w http.ResponseWriter) bool {
+		_, ok := w.(http.Hijacker)
+		return ok
+	})
+	_, p := rw.ResponseWriter.(http.Pusher)
+
+	switch {
+	case f && h && p:
+		return struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, flusher{rw}, hijacker{rw}, pusher{rw}}
+	case f && h:
+		return struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Hijacker
+		}{rw, flusher{rw}, hijacker{rw}}
+	case f && p:
+		return struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Pusher
+		}{rw, flusher{rw}, pusher{rw}}
+	case h && p:
+		return struct {
+			*responseWriterWrapper
+			http.Hijacker
+			http.Pusher
+		}{rw, hijacker{rw}, pusher{rw}}
+	case f:
+		return struct {
+			*responseWriterWrapper
+			http.Flusher
+		}{rw, flusher{rw}}
+	case h:
+		return struct {
+			*responseWriterWrapper
+			http.Hijacker
+		}{rw, hijacker{rw}}
+	case p:
+		return struct {
+			*responseWriterWrapper
+			http.Pusher
+		}{rw, pusher{rw}}
+	default:
+		return rw
 	}
-	return nil
+}
+
+// supports reports whether w, or a writer it wraps, passes check; this is
+// how http.ResponseController finds Flush and Hijack
+func supports(w http.ResponseWriter, check func(http.ResponseWriter) bool) bool {
+	for {
+		if check(w) {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// errorWriter records whether an ErrorHandler produced a response
+type errorWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (ew *errorWriter) WriteHeader(status int) {
+	ew.written = true
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	ew.written = true
+	return ew.ResponseWriter.Write(b)
+}
+
+// Get returns the session of r, see FromContext
+func Get(r *http.Request) *Session {
+	return FromContext(r.Context())
 }
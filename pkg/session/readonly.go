@@ -0,0 +1,126 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// WithReadOnly loads the session of requests matching any of matchers
+// without ever saving or rotating it, which saves a store write per
+// request and keeps a slow GET from overwriting what a concurrent POST
+// saved:
+//
+//	session.WithReadOnly(session.Method(http.MethodGet, http.MethodHead))
+func WithReadOnly(matchers ...Matcher) ConfigOption {
+	return func(c *Config) {
+		c.ReadOnly = Any(matchers...)
+	}
+}
+
+// WithReadOnlyPanic makes writes to a read-only session panic instead of
+// returning ErrSessionReadOnly. DevConfig turns it on.
+func WithReadOnlyPanic(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.ReadOnlyPanic = enabled
+	}
+}
+
+// ReadOnly marks the session of the requests it wraps read-only, for
+// individual routes
+func ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sess := Get(r); sess != nil {
+			sess.SetReadOnly()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// internalKeys are the Data entries the package maintains itself. They stay
+// writable on a read-only session so a GET can still create the CSRF secret
+// and consume flash messages.
+var internalKeys = []string{csrfKey, flashesKey}
+
+// SetReadOnly makes every save of the session fail with ErrSessionReadOnly
+// for the rest of the request. A session just restored from a remember-me
+// cookie stays writable, since the rotated remember token has to reach the
+// client.
+//
+// Changes to the CSRF secret and flash messages are the exception: when
+// nothing else changed, the session is still saved at the end of the
+// request.
+func (s *Session) SetReadOnly() {
+	if s.readOnly || (s.fresh && s.Remembered) {
+		return
+	}
+	s.readOnly = true
+	s.snapshot, s.internal = s.marshalData()
+}
+
+func (s *Session) IsReadOnly() bool {
+	return s.readOnly
+}
+
+// Set stores a value, failing with ErrSessionReadOnly on a read-only session
+func (s *Session) Set(key string, value interface{}) error {
+	if err := s.checkWritable("Set"); err != nil {
+		return err
+	}
+	s.Data[key] = value
+	return nil
+}
+
+// Delete removes a value, failing with ErrSessionReadOnly on a read-only
+// session
+func (s *Session) Delete(key string) error {
+	if err := s.checkWritable("Delete"); err != nil {
+		return err
+	}
+	delete(s.Data, key)
+	return nil
+}
+
+func (s *Session) checkWritable(op string) error {
+	if !s.readOnly {
+		return nil
+	}
+	err := newError(op, ErrSessionReadOnly)
+	if s.readOnlyPanic {
+		panic(err)
+	}
+	return err
+}
+
+// modified reports whether Data of a read-only session was written to
+// directly since SetReadOnly, leaving out internalKeys
+func (s *Session) modified() bool {
+	current, _ := s.marshalData()
+	return !bytes.Equal(current, s.snapshot)
+}
+
+// bookkeepingOnly reports whether a read-only session needs saving for
+// changes to internalKeys alone
+func (s *Session) bookkeepingOnly() bool {
+	current, internal := s.marshalData()
+	return bytes.Equal(current, s.snapshot) && !bytes.Equal(internal, s.internal)
+}
+
+// marshalData encodes Data apart from internalKeys, and internalKeys
+func (s *Session) marshalData() (data, internal []byte) {
+	rest := make(map[string]interface{}, len(s.Data))
+	kept := make(map[string]interface{}, len(internalKeys))
+	for k, v := range s.Data {
+		rest[k] = v
+	}
+	for _, k := range internalKeys {
+		if v, ok := rest[k]; ok {
+			kept[k] = v
+			delete(rest, k)
+		}
+	}
+
+	data, _ = json.Marshal(rest)
+	internal, _ = json.Marshal(kept)
+	return data, internal
+}
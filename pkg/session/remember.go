@@ -0,0 +1,243 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rememberGrace keeps the previous validator usable for a moment so
+// parallel requests carrying the same cookie are not taken for theft.
+const rememberGrace = time.Minute
+
+// selectorSize is the number of random bytes in a selector
+const selectorSize = 12
+
+// RememberToken is the server side of a remember-me cookie. Only a hash of
+// the validator is kept.
+type RememberToken struct {
+	Selector      string
+	ValidatorHash []byte
+	PreviousHash  []byte
+	RotatedAt     time.Time
+	UserID        string
+	ExpiresAt     time.Time
+}
+
+type RememberStore interface {
+	SaveRememberToken(t *RememberToken) error
+	LoadRememberToken(selector string) (*RememberToken, error)
+	DeleteRememberToken(selector string) error
+	DeleteUserRememberTokens(userID string) error
+}
+
+type RememberMe struct {
+	// CookieName defaults to the session cookie name with "_remember"
+	CookieName string
+	// MaxAge defaults to 30 days
+	MaxAge time.Duration
+	// Store defaults to Config.Store when it implements RememberStore
+	Store RememberStore
+}
+
+func WithRememberMe(rm RememberMe) ConfigOption {
+	return func(c *Config) {
+		if rm.MaxAge == 0 {
+			rm.MaxAge = 30 * 24 * time.Hour
+		}
+		c.RememberMe = &rm
+	}
+}
+
+// Remember issues a remember-me cookie for the user of sess, see SetUser.
+// When the session later expires, Load silently starts a new one for that
+// user and marks it Remembered.
+func (m *Manager) Remember(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	store, err := m.rememberStore("Remember")
+	if err != nil {
+		return err
+	}
+	if sess.UserID == "" {
+		return newError("Remember", ErrNoUser)
+	}
+
+	if selector, _, ok := m.rememberCookie(r); ok {
+		_ = store.DeleteRememberToken(selector)
+	}
+	if sess.RememberSelector != "" {
+		_ = store.DeleteRememberToken(sess.RememberSelector)
+	}
+
+	t := &RememberToken{
+		Selector:  randomToken(selectorSize),
+		UserID:    sess.UserID,
+		ExpiresAt: time.Now().Add(m.cfg.RememberMe.MaxAge),
+	}
+
+	cookie, err := m.rotateRememberToken(store, t)
+	if err != nil {
+		return newError("Remember", err)
+	}
+	sess.RememberSelector = t.Selector
+
+	setCookie(w, cookie)
+	m.WriteCacheHeaders(w.Header())
+	return nil
+}
+
+// restoreRemembered turns the fresh session into one for the user of a
+// valid remember-me cookie.
+func (m *Manager) restoreRemembered(r *http.Request, sess *Session) {
+	store, err := m.rememberStore("Load")
+	if err != nil {
+		return
+	}
+
+	selector, validator, ok := m.rememberCookie(r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	name := m.rememberCookieName()
+
+	t, err := store.LoadRememberToken(selector)
+	if err != nil || time.Now().After(t.ExpiresAt) {
+		if err == nil {
+			_ = store.DeleteRememberToken(selector)
+		}
+		sess.pendingCookies = append(sess.pendingCookies, m.namedCookie(name, "", -1))
+		return
+	}
+
+	hash := sha256.Sum256(validator)
+	current := subtle.ConstantTimeCompare(hash[:], t.ValidatorHash) == 1
+	previous := subtle.ConstantTimeCompare(hash[:], t.PreviousHash) == 1 && time.Since(t.RotatedAt) < rememberGrace
+
+	if !current && !previous {
+		// A validator that was already rotated away is being replayed
+		e := newEvent(sess)
+		e.Err = ErrRememberTokenTheft
+		m.emit(m.cfg.Hooks.OnRememberTheft, ctx, e)
+
+		_ = m.RevokeUserSessions(t.UserID)
+		sess.pendingCookies = append(sess.pendingCookies, m.namedCookie(name, "", -1))
+		return
+	}
+
+	if err := m.SetUser(r, sess, t.UserID); err != nil {
+		return
+	}
+	sess.Remembered = true
+	sess.RememberSelector = selector
+
+	if current {
+		cookie, err := m.rotateRememberToken(store, t)
+		if err != nil {
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+			return
+		}
+		sess.pendingCookies = append(sess.pendingCookies, cookie)
+	}
+}
+
+// rotateRememberToken stores a new validator for t and returns its cookie
+func (m *Manager) rotateRememberToken(store RememberStore, t *RememberToken) (*http.Cookie, error) {
+	validator := randomToken(32)
+	raw, _ := base64.RawURLEncoding.DecodeString(validator)
+	hash := sha256.Sum256(raw)
+
+	t.PreviousHash = t.ValidatorHash
+	t.ValidatorHash = hash[:]
+	t.RotatedAt = time.Now()
+
+	if err := store.SaveRememberToken(t); err != nil {
+		return nil, err
+	}
+
+	maxAge := int(time.Until(t.ExpiresAt).Seconds())
+	return m.namedCookie(m.rememberCookieName(), t.Selector+":"+validator, maxAge), nil
+}
+
+// forgetRemembered deletes the remember-me token of the client and the one
+// linked to sess, which may be nil. Clients using a header transport never
+// send the cookie, so the session is the only link to their token.
+func (m *Manager) forgetRemembered(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	store, err := m.rememberStore("Destroy")
+	if err != nil {
+		return nil
+	}
+
+	setCookie(w, m.namedCookie(m.rememberCookieName(), "", -1))
+
+	var errs []error
+	selector, _, ok := m.rememberCookie(r)
+	if ok {
+		errs = append(errs, store.DeleteRememberToken(selector))
+	}
+	if sess != nil && sess.RememberSelector != "" && sess.RememberSelector != selector {
+		errs = append(errs, store.DeleteRememberToken(sess.RememberSelector))
+	}
+	return errors.Join(errs...)
+}
+
+// forgetSessionToken deletes the remember-me token linked to a revoked or
+// evicted session
+func (m *Manager) forgetSessionToken(info SessionInfo) {
+	if info.rememberSelector == "" {
+		return
+	}
+	if store, err := m.rememberStore("RevokeSession"); err == nil {
+		_ = store.DeleteRememberToken(info.rememberSelector)
+	}
+}
+
+func (m *Manager) rememberCookie(r *http.Request) (selector string, validator []byte, ok bool) {
+	c, err := r.Cookie(m.rememberCookieName())
+	if err != nil {
+		return "", nil, false
+	}
+
+	selector, encoded, found := strings.Cut(c.Value, ":")
+	if !found || !randomShape(selector, selectorSize) {
+		return "", nil, false
+	}
+
+	validator, err = base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, false
+	}
+	return selector, validator, true
+}
+
+func (m *Manager) rememberCookieName() string {
+	if m.cfg.RememberMe.CookieName != "" {
+		return m.cfg.RememberMe.CookieName
+	}
+	return m.cfg.CookieName + "_remember"
+}
+
+func (m *Manager) rememberStore(op string) (RememberStore, error) {
+	if m.cfg.RememberMe == nil {
+		return nil, newError(op, ErrRememberUnsupported)
+	}
+	if m.cfg.RememberMe.Store != nil {
+		return m.cfg.RememberMe.Store, nil
+	}
+	if store, ok := m.cfg.Store.(RememberStore); ok {
+		return store, nil
+	}
+	return nil, newError(op, ErrRememberUnsupported)
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = io.ReadFull(rand.Reader, b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
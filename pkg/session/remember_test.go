@@ -0,0 +1,233 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/abmcmanu/sessionx/pkg/store/memory"
+)
+
+type rememberTest struct {
+	t       *testing.T
+	manager *session.Manager
+	thefts  int
+}
+
+func newRememberTest(t *testing.T) *rememberTest {
+	t.Helper()
+
+	rt := &rememberTest{t: t}
+	manager, err := session.NewManager(session.DevConfig(testKey,
+		session.WithStore(memory.NewMemoryStore(memory.Options{})),
+		session.WithRememberMe(session.RememberMe{}),
+		session.WithHooks(session.Hooks{
+			OnRememberTheft: func(ctx context.Context, e session.Event) {
+				if errors.Is(e.Err, session.ErrRememberTokenTheft) {
+					rt.thefts++
+				}
+			},
+		}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.manager = manager
+	return rt
+}
+
+// serve loads the session of a request carrying cookies, lets fn change
+// it and saves it
+func (rt *rememberTest) serve(fn func(w http.ResponseWriter, r *http.Request, sess *session.Session), cookies ...*http.Cookie) (*session.Session, *httptest.ResponseRecorder) {
+	rt.t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	sess, err := rt.manager.Load(r)
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	if fn != nil {
+		fn(rec, r, sess)
+	}
+	if err := rt.manager.Save(rec, sess); err != nil {
+		rt.t.Fatal(err)
+	}
+	return sess, rec
+}
+
+// login signs alice in with a remember-me cookie and returns the session
+// and remember-me cookies
+func (rt *rememberTest) login() (*http.Cookie, *http.Cookie) {
+	rt.t.Helper()
+
+	_, rec := rt.serve(func(w http.ResponseWriter, r *http.Request, sess *session.Session) {
+		if err := rt.manager.SetUser(r, sess, "alice"); err != nil {
+			rt.t.Fatal(err)
+		}
+		if err := rt.manager.Remember(w, r, sess); err != nil {
+			rt.t.Fatal(err)
+		}
+	})
+	return rt.cookie(rec, rt.manager.CookieName()), rt.cookie(rec, rt.manager.CookieName()+"_remember")
+}
+
+// restore starts a session from a remember-me cookie alone and returns it
+// with the rotated cookie, if any
+func (rt *rememberTest) restore(remember *http.Cookie) (*session.Session, *http.Cookie) {
+	rt.t.Helper()
+
+	sess, rec := rt.serve(nil, remember)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == remember.Name && c.MaxAge > 0 {
+			return sess, c
+		}
+	}
+	return sess, nil
+}
+
+func (rt *rememberTest) cookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	rt.t.Helper()
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	rt.t.Fatalf("no %s cookie in response", name)
+	return nil
+}
+
+func TestRememberRestoresAndRotates(t *testing.T) {
+	rt := newRememberTest(t)
+	_, first := rt.login()
+
+	sess, second := rt.restore(first)
+	if !sess.Remembered || sess.UserID != "alice" {
+		t.Fatalf("restored session = %q remembered %v, want alice", sess.UserID, sess.Remembered)
+	}
+	if second == nil || second.Value == first.Value {
+		t.Fatal("validator not rotated on use")
+	}
+
+	sess, _ = rt.restore(second)
+	if !sess.Remembered || sess.UserID != "alice" {
+		t.Fatal("rotated cookie not accepted")
+	}
+}
+
+func TestRememberGraceAcceptsPrevious(t *testing.T) {
+	rt := newRememberTest(t)
+	_, first := rt.login()
+
+	if _, second := rt.restore(first); second == nil {
+		t.Fatal("validator not rotated on use")
+	}
+
+	// A parallel request still carrying the previous validator
+	sess, rotated := rt.restore(first)
+	if !sess.Remembered || sess.UserID != "alice" {
+		t.Fatal("previous validator rejected within the grace window")
+	}
+	if rotated != nil {
+		t.Fatal("previous validator rotated the token again")
+	}
+	if rt.thefts != 0 {
+		t.Fatalf("theft reported %d times", rt.thefts)
+	}
+}
+
+func TestRememberReplayRevokesUser(t *testing.T) {
+	rt := newRememberTest(t)
+	sessionCookie, first := rt.login()
+
+	_, second := rt.restore(first)
+	_, third := rt.restore(second)
+
+	// first is neither the current nor the previous validator any more
+	sess, _ := rt.restore(first)
+	if sess.Remembered || sess.UserID != "" {
+		t.Fatal("replayed validator restored the user")
+	}
+	if rt.thefts != 1 {
+		t.Fatalf("theft reported %d times, want 1", rt.thefts)
+	}
+
+	if sess, _ := rt.restore(third); sess.Remembered {
+		t.Fatal("remember-me token survived the theft")
+	}
+	if sess, _ := rt.serve(nil, sessionCookie); sess.UserID != "" {
+		t.Fatal("session of the user survived the theft")
+	}
+}
+
+func TestDestroyForgetsSessionToken(t *testing.T) {
+	rt := newRememberTest(t)
+	sessionCookie, remember := rt.login()
+
+	// Only the session cookie is sent, as a header-transport client would
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(sessionCookie)
+	sess, err := rt.manager.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = r.WithContext(session.NewContext(r.Context(), sess))
+	if err := rt.manager.Destroy(httptest.NewRecorder(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess, _ := rt.restore(remember); sess.Remembered {
+		t.Fatal("remember-me token of the destroyed session still restores it")
+	}
+}
+
+// brokenRememberStore fails every call
+type brokenRememberStore struct{}
+
+var errRememberDown = errors.New("remember store down")
+
+func (brokenRememberStore) SaveRememberToken(t *session.RememberToken) error { return errRememberDown }
+func (brokenRememberStore) LoadRememberToken(selector string) (*session.RememberToken, error) {
+	return nil, errRememberDown
+}
+func (brokenRememberStore) DeleteRememberToken(selector string) error    { return errRememberDown }
+func (brokenRememberStore) DeleteUserRememberTokens(userID string) error { return errRememberDown }
+
+func TestDestroyWithBrokenRememberStore(t *testing.T) {
+	store := memory.NewMemoryStore(memory.Options{})
+	manager, err := session.NewManager(session.DevConfig(testKey,
+		session.WithStore(store),
+		session.WithRememberMe(session.RememberMe{Store: brokenRememberStore{}}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sess := manager.New()
+	sess.Data["user"] = "alice"
+	if err := manager.Save(httptest.NewRecorder(), sess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: manager.CookieName() + "_remember", Value: "AAAAAAAAAAAAAAAA:AAAA"})
+	r = r.WithContext(session.NewContext(r.Context(), sess))
+	if err := manager.Destroy(httptest.NewRecorder(), r); !errors.Is(err, errRememberDown) {
+		t.Fatalf("Destroy error = %v, want the remember store error", err)
+	}
+
+	if _, err := store.Load(sess.ID); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Fatalf("session still stored after Destroy: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// RevocationChecker lets Load reject sessions that are otherwise valid,
+// which is the only way to end a cookie-only session before MaxAge.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, sess *Session) (bool, error)
+}
+
+// RevocationStore backs the built-in revocation checks: a list of revoked
+// session IDs and epoch counters. A session is revoked once the global
+// epoch, or the epoch of its user, moves past the value stamped on it.
+type RevocationStore interface {
+	RevokeID(ctx context.Context, id string, ttl time.Duration) error
+	IsIDRevoked(ctx context.Context, id string) (bool, error)
+	// Epoch returns the counter of userID; the empty ID is the global epoch
+	Epoch(ctx context.Context, userID string) (uint64, error)
+	IncrEpoch(ctx context.Context, userID string) error
+}
+
+func WithRevocationStore(store RevocationStore) ConfigOption {
+	return func(c *Config) {
+		c.RevocationStore = store
+	}
+}
+
+func WithRevocationChecker(checker RevocationChecker) ConfigOption {
+	return func(c *Config) {
+		c.RevocationChecker = checker
+	}
+}
+
+// RevokeSessionID rejects the session id on every later Load until MaxAge,
+// or MaxSessionAge when longer, has passed
+func (m *Manager) RevokeSessionID(ctx context.Context, id string) error {
+	if m.cfg.RevocationStore == nil {
+		return newError("RevokeSessionID", ErrRevocationUnsupported)
+	}
+	if err := m.cfg.RevocationStore.RevokeID(ctx, id, m.maxSessionAge()); err != nil {
+		return newError("RevokeSessionID", err)
+	}
+	m.notify(ctx, Change{Kind: ChangeRevoked, SessionID: id})
+	return nil
+}
+
+// RevokeAllSessions invalidates every session issued so far
+func (m *Manager) RevokeAllSessions(ctx context.Context) error {
+	if m.cfg.RevocationStore == nil {
+		return newError("RevokeAllSessions", ErrRevocationUnsupported)
+	}
+	if err := m.cfg.RevocationStore.IncrEpoch(ctx, ""); err != nil {
+		return newError("RevokeAllSessions", err)
+	}
+	return nil
+}
+
+func (m *Manager) isRevoked(ctx context.Context, sess *Session) (bool, error) {
+	if rs := m.cfg.RevocationStore; rs != nil {
+		revoked, err := rs.IsIDRevoked(ctx, sess.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+
+		epoch, err := rs.Epoch(ctx, "")
+		if err != nil {
+			return false, err
+		}
+		if sess.Epoch < epoch {
+			return true, nil
+		}
+
+		if sess.UserID != "" {
+			epoch, err := rs.Epoch(ctx, sess.UserID)
+			if err != nil {
+				return false, err
+			}
+			if sess.UserEpoch < epoch {
+				return true, nil
+			}
+		}
+	}
+
+	if m.cfg.RevocationChecker != nil {
+		return m.cfg.RevocationChecker.IsRevoked(ctx, sess)
+	}
+
+	return false, nil
+}
+
+// stampEpoch records the current epoch of userID on sess
+func (m *Manager) stampEpoch(ctx context.Context, sess *Session, userID string) {
+	if m.cfg.RevocationStore == nil {
+		return
+	}
+
+	epoch, err := m.cfg.RevocationStore.Epoch(ctx, userID)
+	if err != nil {
+		m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: sess.ID, Err: err})
+		return
+	}
+
+	if userID == "" {
+		sess.Epoch = epoch
+	} else {
+		sess.UserEpoch = epoch
+	}
+}
@@ -1,6 +1,9 @@
 package session
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
 
 type Session struct {
 	ID        string
@@ -8,19 +11,81 @@ type Session struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	RotatedAt time.Time
+	UserID    string `json:",omitempty"`
+	UserAgent string `json:",omitempty"`
+	Epoch     uint64 `json:",omitempty"`
+	UserEpoch uint64 `json:",omitempty"`
+	// Remembered is set on sessions restored from a remember-me cookie;
+	// ask for the password again before sensitive operations.
+	Remembered bool `json:",omitempty"`
+	// Fingerprint holds keyed hashes of the client attributes selected by
+	// Config.Binding.
+	Fingerprint map[string]string `json:",omitempty"`
+	// MaxAge overrides Config.MaxAge for this session, see SetMaxAge
+	MaxAge time.Duration `json:",omitempty"`
+	// RememberSelector links the session to the remember-me token issued
+	// for or used by it, so revoking the session also revokes the token.
+	RememberSelector string `json:",omitempty"`
+	// RotatedTo is only set on the tombstone a Store keeps under an ID
+	// that was rotated away, see Manager.Watch
+	RotatedTo string `json:",omitempty"`
+
+	destroyed      bool
+	readOnly       bool
+	readOnlyPanic  bool
+	snapshot       []byte
+	internal       []byte
+	skipSave       bool
+	fresh          bool
+	previousID     string
+	pendingCookies []*http.Cookie
+	transport      Transport
+}
+
+// IsDestroyed reports whether Manager.Destroy was called for this session
+func (s *Session) IsDestroyed() bool {
+	return s.destroyed
+}
+
+// IsNew reports whether the session was created by this request rather
+// than sent by the client
+func (s *Session) IsNew() bool {
+	return s.fresh
+}
+
+// SkipSave stops the middleware from saving the session at the end of the
+// request. An explicit Manager.Save still works.
+func (s *Session) SkipSave() {
+	s.skipSave = true
+}
+
+// AutoSave reports whether a middleware should save the session at the end
+// of the request
+func (s *Session) AutoSave() bool {
+	return !s.destroyed && !s.skipSave && (!s.readOnly || s.bookkeepingOnly())
 }
 
+// SetMaxAge gives the session its own lifetime, used for the cookie, the
+// store record and the expiry check on later loads. It is capped at
+// Config.MaxSessionAge.
+func (s *Session) SetMaxAge(d time.Duration) {
+	s.MaxAge = d
+}
+
+// flashesKey holds the flash messages in Data
+const flashesKey = "_flashes"
+
 func (s *Session) AddFlash(key string, value interface{}) {
-	flashes, ok := s.Data["_flashes"].(map[string]interface{})
+	flashes, ok := s.Data[flashesKey].(map[string]interface{})
 	if !ok {
 		flashes = make(map[string]interface{})
-		s.Data["_flashes"] = flashes
+		s.Data[flashesKey] = flashes
 	}
 	flashes[key] = value
 }
 
 func (s *Session) GetFlash(key string) (interface{}, bool) {
-	flashes, ok := s.Data["_flashes"].(map[string]interface{})
+	flashes, ok := s.Data[flashesKey].(map[string]interface{})
 	if !ok {
 		return nil, false
 	}
@@ -29,7 +94,7 @@ func (s *Session) GetFlash(key string) (interface{}, bool) {
 	if exists {
 		delete(flashes, key)
 		if len(flashes) == 0 {
-			delete(s.Data, "_flashes")
+			delete(s.Data, flashesKey)
 		}
 	}
 
@@ -37,7 +102,7 @@ func (s *Session) GetFlash(key string) (interface{}, bool) {
 }
 
 func (s *Session) GetFlashes() map[string]interface{} {
-	flashes, ok := s.Data["_flashes"].(map[string]interface{})
+	flashes, ok := s.Data[flashesKey].(map[string]interface{})
 	if !ok {
 		return make(map[string]interface{})
 	}
@@ -47,12 +112,12 @@ func (s *Session) GetFlashes() map[string]interface{} {
 		result[k] = v
 	}
 
-	delete(s.Data, "_flashes")
+	delete(s.Data, flashesKey)
 	return result
 }
 
 func (s *Session) HasFlash(key string) bool {
-	flashes, ok := s.Data["_flashes"].(map[string]interface{})
+	flashes, ok := s.Data[flashesKey].(map[string]interface{})
 	if !ok {
 		return false
 	}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Matcher selects requests, e.g. the ones Config.Skipper lets through
+// without a session
+type Matcher func(r *http.Request) bool
+
+// WithSkipper makes the middleware pass requests matching any of matchers
+// straight to the handler: no session is loaded or saved, no Set-Cookie is
+// emitted and Get returns nil.
+//
+//	session.WithSkipper(
+//		session.PathPrefix("/static/", "/metrics"),
+//		session.PathGlob("/*.ico"),
+//		session.Method(http.MethodOptions),
+//	)
+func WithSkipper(matchers ...Matcher) ConfigOption {
+	return func(c *Config) {
+		c.Skipper = Any(matchers...)
+	}
+}
+
+// Any matches requests matched by at least one of matchers
+func Any(matchers ...Matcher) Matcher {
+	return func(r *http.Request) bool {
+		for _, m := range matchers {
+			if m(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathPrefix matches request paths starting with one of prefixes
+func PathPrefix(prefixes ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathGlob matches request paths against path.Match patterns, where "*"
+// does not cross a "/". Malformed patterns never match.
+func PathGlob(patterns ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, r.URL.Path); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Method matches requests using one of methods
+func Method(methods ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if strings.EqualFold(r.Method, m) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Skip reports whether r bypasses session handling, see WithSkipper
+func (m *Manager) Skip(r *http.Request) bool {
+	return m.cfg.Skipper != nil && m.cfg.Skipper(r)
+}
@@ -1,7 +1,27 @@
 package session
 
+import "time"
+
 type Store interface {
 	Load(id string) (*Session, error)
 	Save(sess *Session) error
 	Delete(id string) error
-}
\ No newline at end of file
+}
+
+// UserIndex is implemented by stores that keep a secondary index of
+// sessions by Session.UserID.
+type UserIndex interface {
+	ListByUser(userID string) ([]SessionInfo, error)
+	DeleteByUser(userID string) error
+}
+
+// SessionInfo describes a session for account security pages
+type SessionInfo struct {
+	ID        string
+	UserID    string
+	UserAgent string
+	CreatedAt time.Time
+	LastSeen  time.Time
+
+	rememberSelector string
+}
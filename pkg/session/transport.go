@@ -0,0 +1,145 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Transport carries the session token between client and server. The
+// encryption and store layers are the same whatever the transport.
+type Transport interface {
+	// Read returns the token sent with r; name is Config.CookieName
+	Read(r *http.Request, name string) (string, bool)
+	// Write sends the token in c.Value. c carries the configured cookie
+	// attributes; an empty value with a negative MaxAge clears the token.
+	Write(w http.ResponseWriter, c *http.Cookie)
+}
+
+func WithTransport(t Transport) ConfigOption {
+	return func(c *Config) {
+		c.Transport = t
+	}
+}
+
+// CookieTransport is the default transport
+type CookieTransport struct{}
+
+func (CookieTransport) Read(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil || c.Value == "" {
+		return "", false
+	}
+	return c.Value, true
+}
+
+func (CookieTransport) Write(w http.ResponseWriter, c *http.Cookie) {
+	setCookie(w, c)
+}
+
+// HeaderTransport reads the token from a request header and returns it in a
+// response header, for API, mobile and CLI clients that cannot keep cookies.
+type HeaderTransport struct {
+	// Header defaults to X-Session-Token
+	Header string
+	// Scheme is stripped from the request value, e.g. "Bearer" together
+	// with Header "Authorization"
+	Scheme string
+	// ResponseHeader defaults to Header, or X-Session-Token when Header is
+	// Authorization. It is set to an empty value when the session ends.
+	ResponseHeader string
+}
+
+func (t HeaderTransport) Read(r *http.Request, name string) (string, bool) {
+	value := r.Header.Get(t.header())
+	if t.Scheme != "" {
+		prefix := t.Scheme + " "
+		if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+			return "", false
+		}
+		value = value[len(prefix):]
+	}
+
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+func (t HeaderTransport) Write(w http.ResponseWriter, c *http.Cookie) {
+	name := t.ResponseHeader
+	if name == "" {
+		name = t.header()
+		if strings.EqualFold(name, "Authorization") {
+			name = "X-Session-Token"
+		}
+	}
+	w.Header().Set(name, c.Value)
+}
+
+func (t HeaderTransport) header() string {
+	if t.Header == "" {
+		return "X-Session-Token"
+	}
+	return t.Header
+}
+
+// Transports tries each transport in order. The session is written back
+// through the transport that carried it; new sessions use the first one.
+func Transports(ts ...Transport) Transport {
+	return multiTransport(ts)
+}
+
+type multiTransport []Transport
+
+func (mt multiTransport) Read(r *http.Request, name string) (string, bool) {
+	if t := mt.pick(r, name); t != nil {
+		return t.Read(r, name)
+	}
+	return "", false
+}
+
+func (mt multiTransport) Write(w http.ResponseWriter, c *http.Cookie) {
+	if len(mt) > 0 {
+		mt[0].Write(w, c)
+	}
+}
+
+// pick returns the transport carrying a token, or nil
+func (mt multiTransport) pick(r *http.Request, name string) Transport {
+	for _, t := range mt {
+		if _, ok := t.Read(r, name); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// transportFor resolves the transport serving r
+func (m *Manager) transportFor(r *http.Request) Transport {
+	if mt, ok := m.cfg.Transport.(multiTransport); ok {
+		if picked := mt.pick(r, m.cfg.CookieName); picked != nil {
+			return picked
+		}
+	}
+	return m.defaultTransport()
+}
+
+// defaultTransport serves sessions that did not arrive with a token
+func (m *Manager) defaultTransport() Transport {
+	switch t := m.cfg.Transport.(type) {
+	case nil:
+		return CookieTransport{}
+	case multiTransport:
+		if len(t) > 0 {
+			return t[0]
+		}
+		return CookieTransport{}
+	default:
+		return t
+	}
+}
+
+// readToken returns the session token of r and the transport it came with
+func (m *Manager) readToken(r *http.Request) (string, Transport, bool) {
+	t := m.transportFor(r)
+	token, ok := t.Read(r, m.cfg.CookieName)
+	return token, t, ok
+}
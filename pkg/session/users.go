@@ -0,0 +1,174 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"sort"
+)
+
+// LimitPolicy decides what SetUser does when a user already holds
+// Config.MaxSessionsPerUser sessions.
+type LimitPolicy int
+
+const (
+	// LimitReject refuses the new session with ErrSessionLimitReached
+	LimitReject LimitPolicy = iota
+	// LimitEvictOldest revokes the sessions created first
+	LimitEvictOldest
+	// LimitEvictLRU revokes the sessions seen least recently
+	LimitEvictLRU
+)
+
+// SetUser associates sess with a principal so it shows up in UserSessions.
+// The request's User-Agent is recorded for display. When a session limit is
+// configured it is enforced here; concurrent logins may briefly exceed it.
+func (m *Manager) SetUser(r *http.Request, sess *Session, userID string) error {
+	if m.cfg.MaxSessionsPerUser > 0 {
+		if err := m.enforceLimit(r, sess, userID); err != nil {
+			return err
+		}
+	}
+
+	sess.UserID = userID
+	sess.UserAgent = r.UserAgent()
+	m.stampEpoch(r.Context(), sess, userID)
+	return nil
+}
+
+func (m *Manager) enforceLimit(r *http.Request, sess *Session, userID string) error {
+	sessions, err := m.UserSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	others := sessions[:0]
+	for _, s := range sessions {
+		if s.ID != sess.ID {
+			others = append(others, s)
+		}
+	}
+
+	excess := len(others) - m.cfg.MaxSessionsPerUser + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	switch m.cfg.SessionLimitPolicy {
+	case LimitEvictOldest:
+		sort.Slice(others, func(i, j int) bool { return others[i].CreatedAt.Before(others[j].CreatedAt) })
+	case LimitEvictLRU:
+		sort.Slice(others, func(i, j int) bool { return others[i].LastSeen.Before(others[j].LastSeen) })
+	default:
+		return newError("SetUser", ErrSessionLimitReached)
+	}
+
+	for _, s := range others[:excess] {
+		if err := m.cfg.Store.Delete(s.ID); err != nil {
+			m.emit(m.cfg.Hooks.OnStoreError, r.Context(), Event{SessionID: s.ID, Err: err})
+			return newError("SetUser", err)
+		}
+		// Otherwise the evicted device logs straight back in
+		m.forgetSessionToken(s)
+		m.emit(m.cfg.Hooks.OnDestroy, r.Context(), Event{SessionID: s.ID, CreatedAt: s.CreatedAt, UpdatedAt: s.LastSeen})
+		m.notify(r.Context(), Change{Kind: ChangeDestroyed, SessionID: s.ID})
+	}
+
+	return nil
+}
+
+// UserSessions lists the active sessions of userID
+func (m *Manager) UserSessions(userID string) ([]SessionInfo, error) {
+	idx, err := m.userIndex("UserSessions")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := idx.ListByUser(userID)
+	if err != nil {
+		return nil, newError("UserSessions", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes one session of userID, e.g. from a "sign out this
+// device" button. Sessions of other users are never touched.
+func (m *Manager) RevokeSession(userID, id string) error {
+	sessions, err := m.UserSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.ID == id {
+			if err := m.cfg.Store.Delete(id); err != nil {
+				return newError("RevokeSession", err)
+			}
+			m.forgetSessionToken(s)
+			m.notify(context.Background(), Change{Kind: ChangeDestroyed, SessionID: id})
+			return nil
+		}
+	}
+
+	return newError("RevokeSession", ErrSessionNotFound)
+}
+
+// RevokeUserSessions deletes every session of userID ("log out all
+// devices"), typically after a password change. With a RevocationStore the
+// user's epoch is bumped as well, which also covers cookie-only sessions.
+// Remember-me tokens of the user are deleted too.
+func (m *Manager) RevokeUserSessions(userID string) error {
+	if store, err := m.rememberStore("RevokeUserSessions"); err == nil {
+		if err := store.DeleteUserRememberTokens(userID); err != nil {
+			return newError("RevokeUserSessions", err)
+		}
+	}
+
+	idx, err := m.userIndex("RevokeUserSessions")
+	if err != nil && m.cfg.RevocationStore == nil {
+		return err
+	}
+
+	if idx != nil {
+		// Listed first so Watchers can be told which sessions went away
+		var sessions []SessionInfo
+		if m.cfg.Notifier != nil {
+			sessions, _ = idx.ListByUser(userID)
+		}
+
+		if err := idx.DeleteByUser(userID); err != nil {
+			return newError("RevokeUserSessions", err)
+		}
+
+		for _, s := range sessions {
+			m.notify(context.Background(), Change{Kind: ChangeDestroyed, SessionID: s.ID})
+		}
+	}
+
+	if m.cfg.RevocationStore != nil {
+		if err := m.cfg.RevocationStore.IncrEpoch(context.Background(), userID); err != nil {
+			return newError("RevokeUserSessions", err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) userIndex(op string) (UserIndex, error) {
+	idx, ok := m.cfg.Store.(UserIndex)
+	if !ok {
+		return nil, newError(op, ErrUserIndexUnsupported)
+	}
+	return idx, nil
+}
+
+// Info returns the metadata listed by UserSessions
+func (s *Session) Info() SessionInfo {
+	return SessionInfo{
+		ID:        s.ID,
+		UserID:    s.UserID,
+		UserAgent: s.UserAgent,
+		CreatedAt: s.CreatedAt,
+		LastSeen:  s.UpdatedAt,
+
+		rememberSelector: s.RememberSelector,
+	}
+}
@@ -0,0 +1,50 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+	"github.com/abmcmanu/sessionx/pkg/store/memory"
+)
+
+func TestEvictionNotifies(t *testing.T) {
+	notifier := memory.NewNotifier()
+	manager, err := session.NewManager(session.DevConfig(testKey,
+		session.WithStore(memory.NewMemoryStore(memory.Options{})),
+		session.WithMaxSessionsPerUser(1, session.LimitEvictOldest),
+		session.WithNotifier(notifier),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func() *session.Session {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		sess := manager.New()
+		if err := manager.SetUser(r, sess, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.Save(httptest.NewRecorder(), sess); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+
+	first := login()
+	changes, unsubscribe := notifier.Subscribe(first.ID)
+	defer unsubscribe()
+
+	login()
+
+	select {
+	case c := <-changes:
+		if c.Kind != session.ChangeDestroyed || c.SessionID != first.ID {
+			t.Fatalf("change = %+v, want %s destroyed", c, first.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("eviction was not published")
+	}
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abmcmanu/sessionx/pkg/crypto"
+)
+
+const (
+	hostPrefix   = "__Host-"
+	securePrefix = "__Secure-"
+)
+
+// Validate reports every inconsistent setting at once, each wrapping
+// ErrInvalidConfig (or ErrInvalidSecretKey for the key length).
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...))
+	}
+
+	if !crypto.ValidKeySize(c.SecretKey) {
+		errs = append(errs, ErrInvalidSecretKey)
+	}
+	for i, key := range c.OldSecretKeys {
+		if !crypto.ValidKeySize(key) {
+			errs = append(errs, fmt.Errorf("OldSecretKeys[%d]: %w", i, ErrInvalidSecretKey))
+		}
+	}
+
+	if c.CookieName == "" {
+		invalid("CookieName is empty")
+	} else if strings.ContainsAny(c.CookieName, "()<>@,;:\\\"/[]?={} \t") {
+		invalid("CookieName %q contains characters not allowed in a cookie name", c.CookieName)
+	}
+
+	switch c.SameSite {
+	case "", "Lax", "Strict":
+	case "None":
+		if !c.Secure {
+			invalid("SameSite=None requires Secure, browsers reject the cookie otherwise")
+		}
+	default:
+		invalid("SameSite %q is not one of Lax, Strict or None", c.SameSite)
+	}
+
+	if c.Partitioned && (!c.Secure || c.SameSite != "None") {
+		invalid("Partitioned cookies require Secure and SameSite=None")
+	}
+
+	if strings.HasPrefix(c.CookieName, hostPrefix) {
+		if !c.Secure {
+			invalid("%s cookies require Secure", hostPrefix)
+		}
+		if c.Path != "/" {
+			invalid("%s cookies require Path=/ (got %q)", hostPrefix, c.Path)
+		}
+		if c.Domain != "" {
+			invalid("%s cookies must not set a Domain (got %q)", hostPrefix, c.Domain)
+		}
+	} else if strings.HasPrefix(c.CookieName, securePrefix) && !c.Secure {
+		invalid("%s cookies require Secure", securePrefix)
+	}
+
+	if c.MaxAge < 0 {
+		invalid("MaxAge must not be negative")
+	}
+	if c.RotationInterval < 0 {
+		invalid("RotationInterval must not be negative")
+	}
+
+	if c.Binding != nil && (c.Binding.IPv4PrefixBits > 32 || c.Binding.IPv6PrefixBits > 128) {
+		invalid("Binding prefix lengths must be at most 32 (IPv4) and 128 (IPv6)")
+	}
+
+	if c.MaxSessionsPerUser > 0 {
+		if _, ok := c.Store.(UserIndex); !ok {
+			invalid("MaxSessionsPerUser requires a Store implementing UserIndex")
+		}
+	}
+
+	if c.RememberMe != nil && c.RememberMe.Store == nil {
+		if _, ok := c.Store.(RememberStore); !ok {
+			invalid("RememberMe requires a RememberStore")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithHostPrefix renames the cookie with the __Host- prefix and sets what
+// browsers require for it: Secure, Path=/ and no Domain. The cookie is then
+// locked to the exact host. Apply it after WithCookieName, WithPath and
+// WithDomain.
+func WithHostPrefix() ConfigOption {
+	return func(c *Config) {
+		if !strings.HasPrefix(c.CookieName, hostPrefix) {
+			c.CookieName = hostPrefix + strings.TrimPrefix(c.CookieName, securePrefix)
+		}
+		c.Secure = true
+		c.Path = "/"
+		c.Domain = ""
+	}
+}
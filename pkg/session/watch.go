@@ -0,0 +1,293 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+type ChangeKind int
+
+const (
+	// ChangeRotated means the session lives on under Change.NewID
+	ChangeRotated ChangeKind = iota + 1
+	ChangeDestroyed
+	ChangeExpired
+	ChangeRevoked
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeRotated:
+		return "rotated"
+	case ChangeDestroyed:
+		return "destroyed"
+	case ChangeExpired:
+		return "expired"
+	case ChangeRevoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is delivered to the Watchers of a session
+type Change struct {
+	Kind      ChangeKind
+	SessionID string
+	NewID     string `json:",omitempty"`
+}
+
+// Notifier carries changes between the request that makes them and the
+// Watchers following the session, possibly in other processes. The memory
+// store ships an in-process Notifier, the Redis store one based on pub/sub.
+type Notifier interface {
+	// Publish delivers c to the subscribers of c.SessionID
+	Publish(ctx context.Context, c Change) error
+	// Subscribe returns the changes of session id until cancel is called
+	Subscribe(id string) (changes <-chan Change, cancel func())
+}
+
+// WithNotifier makes Destroy, rotation and ID revocation notify Watchers
+// right away instead of at their next revalidation
+func WithNotifier(n Notifier) ConfigOption {
+	return func(c *Config) {
+		c.Notifier = n
+	}
+}
+
+func (m *Manager) notify(ctx context.Context, c Change) {
+	if m.cfg.Notifier == nil || c.SessionID == "" {
+		return
+	}
+	if err := m.cfg.Notifier.Publish(ctx, c); err != nil {
+		m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: c.SessionID, Err: err})
+	}
+}
+
+type WatchOptions struct {
+	// Interval between revalidations against the Store and the revocation
+	// list, 30 seconds by default. Without a Notifier, keep it under five
+	// minutes: rotations are followed through tombstones that last as long.
+	Interval time.Duration
+	// OnChange is called from the watcher goroutine for every change, as
+	// an alternative to reading Changes. When set, Changes only reports the
+	// end of the watch by being closed.
+	OnChange func(Change)
+}
+
+// Watcher follows a session over a long-lived connection such as a
+// WebSocket, see Manager.Watch
+type Watcher struct {
+	manager *Manager
+	opts    WatchOptions
+	changes chan Change
+	done    chan struct{}
+	once    sync.Once
+
+	notifications <-chan Change
+	cancel        func()
+
+	mu      sync.Mutex
+	session *Session
+}
+
+// Watch follows sess, typically the session loaded for a WebSocket
+// upgrade, until it is destroyed, expires or is revoked, ctx is done or
+// Close is called. Rotations are followed transparently and reported: with
+// a Store, through the tombstone left under the previous ID, which a
+// Notifier only makes immediate.
+//
+// Without a Store the watcher only sees what the revocation list and the
+// session's own expiry say; the cookie sent with later requests is never
+// seen, so MaxAge counts from the state at connect time.
+func (m *Manager) Watch(ctx context.Context, sess *Session, opts WatchOptions) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+
+	w := &Watcher{
+		manager: m,
+		opts:    opts,
+		changes: make(chan Change, 4),
+		done:    make(chan struct{}),
+		// The request goroutine may keep using sess
+		session: sess.clone(),
+		cancel:  func() {},
+	}
+	// Subscribed before returning so no change made after Watch is missed
+	w.subscribe(sess.ID)
+	go w.run(ctx, sess.ID)
+	return w
+}
+
+// Changes delivers every change and is closed once the watcher stops
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
+
+// Session returns the latest state of the session
+func (w *Watcher) Session() *Session {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.session
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) run(ctx context.Context, id string) {
+	defer close(w.changes)
+	defer func() { w.cancel() }()
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		var c Change
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.done:
+			return
+		case n, ok := <-w.notifications:
+			if !ok {
+				w.notifications = nil
+				continue
+			}
+			c = n
+		case <-ticker.C:
+			var ok bool
+			if c, ok = w.revalidate(ctx, id); !ok {
+				continue
+			}
+		}
+
+		// A watcher catching up on tombstones may follow several rotations
+		for c.Kind == ChangeRotated {
+			id = c.NewID
+			w.subscribe(id)
+			next := w.follow(ctx, id)
+			if !w.deliver(ctx, c) {
+				return
+			}
+			c = next
+		}
+
+		if c.Kind != 0 {
+			w.deliver(ctx, c)
+			return
+		}
+	}
+}
+
+func (w *Watcher) subscribe(id string) {
+	w.cancel()
+	if w.manager.cfg.Notifier != nil {
+		w.notifications, w.cancel = w.manager.cfg.Notifier.Subscribe(id)
+	}
+}
+
+// follow switches to the rotated session id and returns the next change
+// already visible for it, if any
+func (w *Watcher) follow(ctx context.Context, id string) Change {
+	if w.manager.cfg.Store != nil {
+		c, _ := w.revalidate(ctx, id)
+		if c.Kind == ChangeDestroyed {
+			// Renew leaves the tombstone before the new record is saved;
+			// the next revalidation tells a missing save from a destroy
+			return Change{}
+		}
+		return c
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rotated := *w.session
+	rotated.ID = id
+	w.session = &rotated
+	return Change{}
+}
+
+// deliver hands c to OnChange or Changes, giving up when the watcher stops
+func (w *Watcher) deliver(ctx context.Context, c Change) bool {
+	if w.opts.OnChange != nil {
+		w.opts.OnChange(c)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-w.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	select {
+	case w.changes <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-w.done:
+		return false
+	}
+}
+
+// revalidate checks session id and reports a change when it ended
+func (w *Watcher) revalidate(ctx context.Context, id string) (Change, bool) {
+	m := w.manager
+	sess := w.Session()
+
+	if m.cfg.Store != nil {
+		loaded, err := m.cfg.Store.Load(id)
+		if errors.Is(err, ErrSessionNotFound) {
+			return Change{Kind: ChangeDestroyed, SessionID: id}, true
+		}
+		if err != nil {
+			m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: id, Err: err})
+			return Change{}, false
+		}
+		if loaded.RotatedTo != "" {
+			return Change{Kind: ChangeRotated, SessionID: id, NewID: loaded.RotatedTo}, true
+		}
+		sess = loaded
+
+		w.mu.Lock()
+		w.session = loaded
+		w.mu.Unlock()
+	}
+
+	if maxAge := m.maxAge(sess); maxAge > 0 && time.Since(sess.UpdatedAt) > maxAge {
+		return Change{Kind: ChangeExpired, SessionID: id}, true
+	}
+
+	revoked, err := m.isRevoked(ctx, sess)
+	if err != nil {
+		m.emit(m.cfg.Hooks.OnStoreError, ctx, Event{SessionID: id, Err: err})
+		return Change{}, false
+	}
+	if revoked {
+		return Change{Kind: ChangeRevoked, SessionID: id}, true
+	}
+	return Change{}, false
+}
+
+// clone copies s for another goroutine; Data is copied through JSON like a
+// Store would
+func (s *Session) clone() *Session {
+	c := *s
+	c.Data = map[string]interface{}{}
+	if raw, err := json.Marshal(s.Data); err == nil {
+		_ = json.Unmarshal(raw, &c.Data)
+	}
+	c.Fingerprint = maps.Clone(s.Fingerprint)
+	c.pendingCookies = nil
+	return &c
+}
@@ -1 +1,228 @@
 package memory
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+)
+
+var (
+	ErrSessionNotFound = session.ErrSessionNotFound
+	ErrInvalidSession  = session.ErrInvalidSession
+)
+
+// MemoryStore keeps sessions in process memory. It suits tests, development
+// and single-instance deployments; sessions are lost on restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]entry
+	users    map[string]map[string]struct{}
+	ttl      time.Duration
+
+	remember      map[string]*session.RememberToken
+	rememberUsers map[string]map[string]struct{}
+
+	stop chan struct{}
+	once sync.Once
+}
+
+type entry struct {
+	data    []byte
+	userID  string
+	expires time.Time
+}
+
+type Options struct {
+	TTL time.Duration
+	// CleanupInterval sweeps expired sessions in the background. Zero
+	// disables the sweep; expired sessions are then only dropped on access.
+	CleanupInterval time.Duration
+}
+
+func NewMemoryStore(opts Options) *MemoryStore {
+	if opts.TTL == 0 {
+		opts.TTL = 24 * time.Hour
+	}
+
+	s := &MemoryStore{
+		sessions: make(map[string]entry),
+		users:    make(map[string]map[string]struct{}),
+		ttl:      opts.TTL,
+		stop:     make(chan struct{}),
+
+		remember:      make(map[string]*session.RememberToken),
+		rememberUsers: make(map[string]map[string]struct{}),
+	}
+
+	if opts.CleanupInterval > 0 {
+		go s.cleanup(opts.CleanupInterval)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) Load(id string) (*session.Session, error) {
+	s.mu.RLock()
+	e, ok := s.sessions[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	if time.Now().After(e.expires) {
+		_ = s.Delete(id)
+		return nil, ErrSessionNotFound
+	}
+
+	var sess session.Session
+	if err := json.Unmarshal(e.data, &sess); err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	return &sess, nil
+}
+
+func (s *MemoryStore) Save(sess *session.Session) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.sessions[sess.ID]; ok && old.userID != sess.UserID {
+		s.unindex(old.userID, sess.ID)
+	}
+
+	s.sessions[sess.ID] = entry{
+		data:    data,
+		userID:  sess.UserID,
+		expires: time.Now().Add(s.expiry(sess)),
+	}
+
+	if sess.UserID != "" {
+		ids, ok := s.users[sess.UserID]
+		if !ok {
+			ids = make(map[string]struct{})
+			s.users[sess.UserID] = ids
+		}
+		ids[sess.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+// expiry is the TTL of the store, or the session's own MaxAge
+func (s *MemoryStore) expiry(sess *session.Session) time.Duration {
+	if sess.MaxAge > 0 {
+		return sess.MaxAge
+	}
+	return s.ttl
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete(id)
+	return nil
+}
+
+// ListByUser returns the unexpired sessions of userID
+func (s *MemoryStore) ListByUser(userID string) ([]session.SessionInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var infos []session.SessionInfo
+	now := time.Now()
+	for id := range s.users[userID] {
+		e := s.sessions[id]
+		if now.After(e.expires) {
+			s.delete(id)
+			continue
+		}
+
+		var sess session.Session
+		if err := json.Unmarshal(e.data, &sess); err != nil {
+			return nil, ErrInvalidSession
+		}
+		infos = append(infos, sess.Info())
+	}
+
+	return infos, nil
+}
+
+func (s *MemoryStore) DeleteByUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.users[userID] {
+		s.delete(id)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+}
+
+// DeleteExpired drops every expired session
+func (s *MemoryStore) DeleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, e := range s.sessions {
+		if now.After(e.expires) {
+			s.delete(id)
+		}
+	}
+
+	for selector, t := range s.remember {
+		if now.After(t.ExpiresAt) {
+			s.deleteRememberToken(selector)
+		}
+	}
+}
+
+// Close stops the background cleanup
+func (s *MemoryStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.DeleteExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) delete(id string) {
+	if e, ok := s.sessions[id]; ok {
+		s.unindex(e.userID, id)
+		delete(s.sessions, id)
+	}
+}
+
+func (s *MemoryStore) unindex(userID, id string) {
+	if ids, ok := s.users[userID]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(s.users, userID)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+)
+
+// Notifier implements session.Notifier within one process. Use the Redis
+// variant when running more than one instance.
+type Notifier struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan session.Change]struct{}
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{subscribers: make(map[string]map[chan session.Change]struct{})}
+}
+
+// Publish never blocks; a subscriber that is not keeping up misses c and
+// relies on its next revalidation.
+func (n *Notifier) Publish(ctx context.Context, c session.Change) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.subscribers[c.SessionID] {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) Subscribe(id string) (<-chan session.Change, func()) {
+	ch := make(chan session.Change, 1)
+
+	n.mu.Lock()
+	if n.subscribers[id] == nil {
+		n.subscribers[id] = make(map[chan session.Change]struct{})
+	}
+	n.subscribers[id][ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			delete(n.subscribers[id], ch)
+			if len(n.subscribers[id]) == 0 {
+				delete(n.subscribers, id)
+			}
+		})
+	}
+	return ch, cancel
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/abmcmanu/sessionx/pkg/session"
+)
+
+func (s *MemoryStore) SaveRememberToken(t *session.RememberToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *t
+	s.remember[t.Selector] = &copied
+
+	ids, ok := s.rememberUsers[t.UserID]
+	if !ok {
+		ids = make(map[string]struct{})
+		s.rememberUsers[t.UserID] = ids
+	}
+	ids[t.Selector] = struct{}{}
+
+	return nil
+}
+
+func (s *MemoryStore) LoadRememberToken(selector string) (*session.RememberToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.remember[selector]
+	if !ok || time.Now().After(t.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+
+	copied := *t
+	return &copied, nil
+}
+
+func (s *MemoryStore) DeleteRememberToken(selector string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteRememberToken(selector)
+	return nil
+}
+
+func (s *MemoryStore) DeleteUserRememberTokens(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for selector := range s.rememberUsers[userID] {
+		s.deleteRememberToken(selector)
+	}
+	return nil
+}
+
+func (s *MemoryStore) deleteRememberToken(selector string) {
+	t, ok := s.remember[selector]
+	if !ok {
+		return
+	}
+
+	delete(s.remember, selector)
+	if ids, ok := s.rememberUsers[t.UserID]; ok {
+		delete(ids, selector)
+		if len(ids) == 0 {
+			delete(s.rememberUsers, t.UserID)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationStore implements session.RevocationStore in process memory.
+// Revocations are not shared between instances; use the Redis variant when
+// running more than one.
+type RevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+	epochs  map[string]uint64
+}
+
+func NewRevocationStore() *RevocationStore {
+	return &RevocationStore{
+		revoked: make(map[string]time.Time),
+		epochs:  make(map[string]uint64),
+	}
+}
+
+func (s *RevocationStore) RevokeID(ctx context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for revokedID, expires := range s.revoked {
+		if !expires.IsZero() && now.After(expires) {
+			delete(s.revoked, revokedID)
+		}
+	}
+
+	// A zero expiry never expires, like a Redis key set without TTL
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+	s.revoked[id] = expires
+	return nil
+}
+
+func (s *RevocationStore) IsIDRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expires, ok := s.revoked[id]
+	return ok && (expires.IsZero() || time.Now().Before(expires)), nil
+}
+
+func (s *RevocationStore) Epoch(ctx context.Context, userID string) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.epochs[userID], nil
+}
+
+func (s *RevocationStore) IncrEpoch(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.epochs[userID]++
+	return nil
+}